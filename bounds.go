@@ -0,0 +1,214 @@
+package discover
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// --------- UNIVERSE BOUNDS ---------
+
+// Bounds is the axis-aligned box around a set of planets.
+type Bounds struct {
+	Min, Max [3]float64
+	Centroid [3]float64 // mean planet position
+	Planets  int        // planets counted; 0 leaves the rest zero
+}
+
+// Center returns the middle of the box.
+func (b Bounds) Center() [3]float64 {
+	return [3]float64{(b.Min[0] + b.Max[0]) / 2, (b.Min[1] + b.Max[1]) / 2, (b.Min[2] + b.Max[2]) / 2}
+}
+
+// Size returns the box's extent on each axis.
+func (b Bounds) Size() [3]float64 {
+	return [3]float64{b.Max[0] - b.Min[0], b.Max[1] - b.Min[1], b.Max[2] - b.Min[2]}
+}
+
+// UniverseBounds returns the bounding box and centroid of every planet with
+// finite coordinates, so cameras and maps can frame the discovered world.
+func (d *Discover) UniverseBounds() Bounds {
+	t := d.planetIndex()
+	var b Bounds
+	for i, e := range t.pts {
+		if i == 0 {
+			b.Min, b.Max = e.p, e.p
+		}
+		for a := 0; a < 3; a++ {
+			b.Min[a] = math.Min(b.Min[a], e.p[a])
+			b.Max[a] = math.Max(b.Max[a], e.p[a])
+			b.Centroid[a] += e.p[a]
+		}
+	}
+	if b.Planets = len(t.pts); b.Planets > 0 {
+		for a := range b.Centroid {
+			b.Centroid[a] /= float64(b.Planets)
+		}
+	}
+	return b
+}
+
+// --- convex hull ---
+
+// ConvexHull returns the names of the planets on the convex hull of all
+// planets, sorted. If the planets are coplanar the hull is their outline in
+// that plane; if collinear, the two ends.
+func (d *Discover) ConvexHull() []string {
+	defer perfTrack("ConvexHull")()
+	t := d.planetIndex()
+	pts := make([][3]float64, len(t.pts))
+	for i, e := range t.pts {
+		pts[i] = e.p
+	}
+	idx := convexHull(pts)
+	names := make([]string, 0, len(idx))
+	for _, i := range idx {
+		names = append(names, t.pts[i].name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sub3(a, b [3]float64) [3]float64 { return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+
+func dot3(a, b [3]float64) float64 { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+
+// convexHull returns the indexes of the hull vertices of pts, using an
+// incremental 3D hull and falling back to a planar hull for flat input.
+func convexHull(pts [][3]float64) []int {
+	if len(pts) == 0 {
+		return nil
+	}
+	// Pick a well-spread starting simplex: the farthest point from pts[0],
+	// the farthest from that line, then the farthest from that plane.
+	scale := 0.0
+	for _, p := range pts {
+		for a := 0; a < 3; a++ {
+			scale = math.Max(scale, math.Abs(p[a]))
+		}
+	}
+	eps := math.Max(scale, 1) * 1e-9
+	farthest := func(score func([3]float64) float64) (int, float64) {
+		best, bestS := -1, -1.0
+		for i, p := range pts {
+			if s := score(p); s > bestS {
+				best, bestS = i, s
+			}
+		}
+		return best, bestS
+	}
+	a := 0
+	b, ab := farthest(func(p [3]float64) float64 { return dist2(p, pts[a]) })
+	if math.Sqrt(ab) <= eps {
+		return []int{a}
+	}
+	dir := normalize3(sub3(pts[b], pts[a]))
+	c, ac := farthest(func(p [3]float64) float64 { return vecLen(cross3(dir, sub3(p, pts[a]))) })
+	if ac <= eps {
+		return []int{a, b}
+	}
+	n := normalize3(cross3(sub3(pts[b], pts[a]), sub3(pts[c], pts[a])))
+	e, ae := farthest(func(p [3]float64) float64 { return math.Abs(dot3(n, sub3(p, pts[a]))) })
+	if ae <= eps {
+		return planarHull(pts, pts[a], dir, cross3(n, dir))
+	}
+
+	type face struct {
+		v   [3]int
+		n   [3]float64
+		off float64
+	}
+	makeFace := func(i, j, k int) face {
+		n := normalize3(cross3(sub3(pts[j], pts[i]), sub3(pts[k], pts[i])))
+		return face{v: [3]int{i, j, k}, n: n, off: dot3(n, pts[i])}
+	}
+	faces := []face{makeFace(a, b, c), makeFace(a, c, e), makeFace(a, e, b), makeFace(b, e, c)}
+	if dot3(faces[0].n, pts[e])-faces[0].off > 0 { // e in front of abc: flip the winding
+		faces = []face{makeFace(a, c, b), makeFace(a, b, e), makeFace(a, e, c), makeFace(b, c, e)}
+	}
+
+	outside := func(f face, p int) bool { return dot3(f.n, pts[p])-f.off > eps }
+	for p := range pts {
+		if !slices.ContainsFunc(faces, func(f face) bool { return outside(f, p) }) {
+			continue // inside the hull so far
+		}
+		var keep, visible []face
+		for _, f := range faces {
+			if outside(f, p) {
+				visible = append(visible, f)
+			} else {
+				keep = append(keep, f)
+			}
+		}
+		// Horizon edges are edges of visible faces whose twin is not visible.
+		edges := make(map[[2]int]bool, 3*len(visible))
+		for _, f := range visible {
+			for k := 0; k < 3; k++ {
+				edges[[2]int{f.v[k], f.v[(k+1)%3]}] = true
+			}
+		}
+		for _, f := range visible {
+			for k := 0; k < 3; k++ {
+				u, v := f.v[k], f.v[(k+1)%3]
+				if !edges[[2]int{v, u}] {
+					keep = append(keep, makeFace(u, v, p))
+				}
+			}
+		}
+		faces = keep
+	}
+
+	seen := make(map[int]bool)
+	var out []int
+	for _, f := range faces {
+		for _, v := range f.v {
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func vecLen(v [3]float64) float64 { return math.Sqrt(dot3(v, v)) }
+
+// planarHull is Andrew's monotone chain on pts projected onto the plane
+// through origin spanned by the unit vectors u and v.
+func planarHull(pts [][3]float64, origin, u, v [3]float64) []int {
+	type p2 struct {
+		x, y float64
+		i    int
+	}
+	ps := make([]p2, len(pts))
+	for i, p := range pts {
+		r := sub3(p, origin)
+		ps[i] = p2{dot3(r, u), dot3(r, v), i}
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].x != ps[j].x {
+			return ps[i].x < ps[j].x
+		}
+		return ps[i].y < ps[j].y
+	})
+	turn := func(o, a, b p2) float64 { return (a.x-o.x)*(b.y-o.y) - (a.y-o.y)*(b.x-o.x) }
+	var hull []p2
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range ps {
+			for len(hull) >= start+2 && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1] // last point starts the other chain
+		for i, j := 0, len(ps)-1; i < j; i, j = i+1, j-1 {
+			ps[i], ps[j] = ps[j], ps[i]
+		}
+	}
+	out := make([]int, len(hull))
+	for k, p := range hull {
+		out[k] = p.i
+	}
+	return out
+}
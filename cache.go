@@ -0,0 +1,160 @@
+package discover
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// --------- DERIVED DATA CACHE ---------
+//
+// Expensive values computed from a single planet (spawn shells, grids,
+// partitions) are memoized per planet and tied to the planet's fingerprint.
+// When a rescan changes a planet the fingerprint changes and its entries are
+// dropped, so static planets pay for each computation once.
+
+// PlanetFingerprint is a hash of everything a scan reports about a planet.
+func PlanetFingerprint(p PlanetRecord) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(p.Name))
+	h.Write([]byte{0})
+	var buf [8]byte
+	putFloat := func(f float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+		h.Write(buf[:])
+	}
+	putInt := func(i int) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(i))
+		h.Write(buf[:])
+	}
+	for _, c := range p.Coordinates {
+		putFloat(c)
+	}
+	putInt(p.Seed)
+	putInt(p.BiomeType)
+	for _, locs := range [][][3]float64{p.ResourceLocations, p.TreeLocations} {
+		putInt(len(locs))
+		for _, l := range locs {
+			for _, c := range l {
+				putFloat(c)
+			}
+		}
+	}
+	h.Write([]byte(p.Host))
+	h.Write([]byte{0})
+	putInt(p.Port)
+	return h.Sum64()
+}
+
+type derivedEntry struct {
+	fingerprint uint64
+	values      map[string]any
+}
+
+type derivedCache struct {
+	mu      sync.Mutex
+	entries map[string]*derivedEntry // planet name -> entry
+}
+
+// Derived returns the value cached under key for the named planet, calling
+// compute when it is missing or the planet changed since it was stored.
+// Errors are not cached. Callers must treat the returned value as read-only.
+func (d *Discover) Derived(planetName, key string, compute func(PlanetRecord) (any, error)) (any, error) {
+	d.mu.Lock()
+	planet, ok := d.Planets[planetName]
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("planet %s not found", planetName)
+	}
+	fp := PlanetFingerprint(planet)
+
+	c := &d.derived
+	c.mu.Lock()
+	if e, ok := c.entries[planetName]; ok && e.fingerprint == fp {
+		if v, ok := e.values[key]; ok {
+			c.mu.Unlock()
+			return v, nil
+		}
+	}
+	c.mu.Unlock()
+
+	v, err := compute(planet)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*derivedEntry)
+	}
+	e, ok := c.entries[planetName]
+	if !ok || e.fingerprint != fp {
+		e = &derivedEntry{fingerprint: fp, values: make(map[string]any)}
+		c.entries[planetName] = e
+	}
+	e.values[key] = v
+	return v, nil
+}
+
+// InvalidateDerived drops cached values for the named planets, or for every
+// planet when called without names.
+func (d *Discover) InvalidateDerived(planetNames ...string) {
+	c := &d.derived
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(planetNames) == 0 {
+		c.entries = nil
+		return
+	}
+	for _, name := range planetNames {
+		delete(c.entries, name)
+	}
+}
+
+// invalidateChanged drops cache entries whose planet is gone or whose
+// fingerprint no longer matches, and marks the planet index stale. Called
+// after scans merge new data.
+func (d *Discover) invalidateChanged() {
+	d.RebuildIndex()
+	c := &d.derived
+	c.mu.Lock()
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+
+	// Fingerprint the cached planets under d.mu, then prune under c.mu; the
+	// two locks are never held together.
+	current := make(map[string]uint64, len(names))
+	d.mu.Lock()
+	for _, name := range names {
+		if p, ok := d.Planets[name]; ok {
+			current[name] = PlanetFingerprint(p)
+		}
+	}
+	d.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		e, ok := c.entries[name]
+		if !ok {
+			continue
+		}
+		if fp, ok := current[name]; !ok || fp != e.fingerprint {
+			delete(c.entries, name)
+		}
+	}
+}
+
+func copyPoints(points [][]float64) [][]float64 {
+	out := make([][]float64, len(points))
+	for i, p := range points {
+		out[i] = append([]float64(nil), p...)
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+)
+
+// --------- ENGINE CHUNK COORDINATES ---------
+
+// ChunkCoord indexes a chunk in the engine's world grid.
+type ChunkCoord [3]int
+
+// WorldToChunk returns the chunk containing a world-space point. Chunks are
+// cubes of chunkSize units with chunk (0,0,0) spanning [0, chunkSize).
+// chunkSize must be positive and finite, and point a finite 3D position.
+func WorldToChunk(point []float64, chunkSize float64) (ChunkCoord, error) {
+	if err := checkCellSize("chunk", chunkSize); err != nil {
+		return ChunkCoord{}, err
+	}
+	if len(point) < 3 {
+		return ChunkCoord{}, fmt.Errorf("point %v: need 3 coordinates", point)
+	}
+	var c ChunkCoord
+	for i := 0; i < 3; i++ {
+		f := math.Floor(point[i] / chunkSize)
+		if math.IsNaN(f) || f < math.MinInt || f >= math.MaxInt {
+			return ChunkCoord{}, fmt.Errorf("point %v: outside the chunk grid", point)
+		}
+		c[i] = int(f)
+	}
+	return c, nil
+}
+
+// WorldToVoxel returns the chunk containing point and the voxel index within
+// that chunk, for voxels of voxelSize units. Both sizes must be positive and
+// finite.
+func WorldToVoxel(point []float64, chunkSize, voxelSize float64) (ChunkCoord, [3]int, error) {
+	if err := checkCellSize("voxel", voxelSize); err != nil {
+		return ChunkCoord{}, [3]int{}, err
+	}
+	chunk, err := WorldToChunk(point, chunkSize)
+	if err != nil {
+		return ChunkCoord{}, [3]int{}, err
+	}
+	var voxel [3]int
+	for i := 0; i < 3; i++ {
+		local := point[i] - float64(chunk[i])*chunkSize
+		voxel[i] = int(math.Floor(local / voxelSize))
+	}
+	return chunk, voxel, nil
+}
+
+func checkCellSize(what string, size float64) error {
+	if !(size > 0) || math.IsInf(size, 1) {
+		return fmt.Errorf("%s size %v must be positive and finite", what, size)
+	}
+	return nil
+}
+
+// ChunkOrigin returns the world position of a chunk's minimum corner.
+func ChunkOrigin(c ChunkCoord, chunkSize float64) []float64 {
+	return []float64{float64(c[0]) * chunkSize, float64(c[1]) * chunkSize, float64(c[2]) * chunkSize}
+}
+
+// ChunkBounds is an inclusive range of chunks.
+type ChunkBounds struct {
+	Min ChunkCoord
+	Max ChunkCoord
+}
+
+// Span is the number of chunks along each axis.
+func (b ChunkBounds) Span() [3]int {
+	return [3]int{b.Max[0] - b.Min[0] + 1, b.Max[1] - b.Min[1] + 1, b.Max[2] - b.Min[2] + 1}
+}
+
+// Count is the total number of chunks in the bounds.
+func (b ChunkBounds) Count() int {
+	s := b.Span()
+	return s[0] * s[1] * s[2]
+}
+
+// ChunkBoundsContaining returns the smallest chunk range covering all points,
+// e.g. to check a spawn plan against the engine's streaming limits before
+// executing it. ok is false when points is empty; any point WorldToChunk
+// rejects is an error.
+func ChunkBoundsContaining(points [][]float64, chunkSize float64) (bounds ChunkBounds, ok bool, err error) {
+	if err := checkCellSize("chunk", chunkSize); err != nil {
+		return ChunkBounds{}, false, err
+	}
+	for i, p := range points {
+		c, err := WorldToChunk(p, chunkSize)
+		if err != nil {
+			return ChunkBounds{}, false, err
+		}
+		if i == 0 {
+			bounds = ChunkBounds{Min: c, Max: c}
+			continue
+		}
+		for a := 0; a < 3; a++ {
+			bounds.Min[a] = min(bounds.Min[a], c[a])
+			bounds.Max[a] = max(bounds.Max[a], c[a])
+		}
+	}
+	return bounds, len(points) > 0, nil
+}
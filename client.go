@@ -0,0 +1,242 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// --- Persistent pod client ---
+
+// PodClient keeps one authenticated connection to a pod open across calls.
+// It dials lazily on first use and redials after a transport error. A
+// PodClient is safe for concurrent use: calls are serialized, unless
+// Config.Multiplex is set, in which case they share the connection
+// concurrently and replies are matched by request_id.
+type PodClient struct {
+	PodRef
+
+	cfg      Config
+	mu       sync.Mutex
+	conn     msgTransport
+	mux      *muxConn    // set when Config.Multiplex is on
+	pool     *ClientPool // nil for standalone clients
+	lastUsed time.Time
+
+	stateMu  sync.Mutex
+	lastSeen time.Time
+	alive    bool
+	hbStop   chan struct{}
+}
+
+// NewPodClient returns a client for pod using cfg's auth, framing and
+// timeout settings. No connection is made until it is needed.
+func NewPodClient(cfg Config, pod PodRef) *PodClient {
+	return &PodClient{PodRef: pod, cfg: cfg}
+}
+
+var errNonJSONReply = errors.New("pod reply is not valid JSON")
+
+// SendCommand sends an arbitrary command and returns the pod's raw JSON reply.
+// cmd may be a string or raw JSON, which is sent as-is, or any value that
+// marshals to JSON. Cancelling ctx aborts the exchange and drops the
+// connection.
+func (c *PodClient) SendCommand(ctx context.Context, cmd any) (json.RawMessage, error) {
+	msg, err := encodeCommand(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("encode command: %w", err)
+	}
+	resp, err := c.roundTrip(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(resp)) {
+		return nil, fmt.Errorf("%w: %q", errNonJSONReply, resp)
+	}
+	return json.RawMessage(resp), nil
+}
+
+// roundTrip sends one message and reads one reply on the shared connection.
+func (c *PodClient) roundTrip(ctx context.Context, msg string) (string, error) {
+	if c.cfg.Multiplex {
+		return c.muxRoundTrip(ctx, msg)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if c.conn == nil {
+		if err := c.connectLocked(ctx); err != nil {
+			c.markSeen(false)
+			return "", err
+		}
+	}
+	c.lastUsed = time.Now()
+
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	resp, err := c.exchange(msg)
+	c.markSeen(err == nil)
+	if err != nil {
+		c.dropLocked()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", err
+	}
+	return resp, nil
+}
+
+// connectLocked dials and authenticates, taking a pool slot if pooled.
+func (c *PodClient) connectLocked(ctx context.Context) error {
+	if c.pool != nil {
+		if err := c.pool.acquire(ctx, c); err != nil {
+			return err
+		}
+	}
+	conn, err := dialPod(c.cfg, c.PodRef)
+	if err != nil {
+		if c.pool != nil {
+			c.pool.release(c.Host)
+		}
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *PodClient) exchange(msg string) (string, error) {
+	if err := c.conn.sendMsg(msg); err != nil {
+		return "", err
+	}
+	return c.conn.readMsg()
+}
+
+func (c *PodClient) dropLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.mux = nil
+	if c.pool != nil {
+		c.pool.release(c.Host)
+	}
+	return err
+}
+
+// Close stops any heartbeat and closes the underlying connection, if any.
+// The client may be reused afterwards; the next call redials.
+func (c *PodClient) Close() error {
+	c.StopHeartbeat()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropLocked()
+}
+
+// isIdle reports whether the client holds a connection and no call is in flight.
+func (c *PodClient) isIdle() bool {
+	if !c.mu.TryLock() {
+		return false
+	}
+	defer c.mu.Unlock()
+	return c.conn != nil && (c.mux == nil || c.mux.inFlight() == 0)
+}
+
+func (c *PodClient) idleSince() time.Time {
+	if !c.mu.TryLock() {
+		return time.Now()
+	}
+	defer c.mu.Unlock()
+	return c.lastUsed
+}
+
+// closeIfIdle drops the connection if it has been unused for at least d and
+// no call is in flight.
+func (c *PodClient) closeIfIdle(d time.Duration) {
+	if !c.mu.TryLock() {
+		return
+	}
+	defer c.mu.Unlock()
+	if c.mux != nil && c.mux.inFlight() > 0 {
+		return
+	}
+	if c.conn != nil && time.Since(c.lastUsed) >= d {
+		c.dropLocked()
+	}
+}
+
+// --- Heartbeat ---
+
+const heartbeatMsg = `{"type":"heartbeat"}`
+
+func (c *PodClient) markSeen(ok bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.alive = ok
+	if ok {
+		c.lastSeen = time.Now()
+	}
+}
+
+// LastSeen is the time of the last successful exchange with the pod.
+func (c *PodClient) LastSeen() time.Time {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.lastSeen
+}
+
+// Alive reports whether the most recent exchange (command or heartbeat) succeeded.
+func (c *PodClient) Alive() bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.alive
+}
+
+// StartHeartbeat sends {"type":"heartbeat"} every interval unless other
+// traffic already proved the pod alive within it. A failed heartbeat drops
+// the connection and immediately retries once on a fresh one; Alive reflects
+// the outcome. Calling StartHeartbeat again replaces the previous loop.
+func (c *PodClient) StartHeartbeat(interval time.Duration) {
+	c.StopHeartbeat()
+	stop := make(chan struct{})
+	c.stateMu.Lock()
+	c.hbStop = stop
+	c.stateMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if c.Alive() && time.Since(c.LastSeen()) < interval {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if _, err := c.roundTrip(ctx, heartbeatMsg); err != nil {
+					c.roundTrip(ctx, heartbeatMsg) // reconnect attempt
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// StopHeartbeat stops the heartbeat loop, if running.
+func (c *PodClient) StopHeartbeat() {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.hbStop != nil {
+		close(c.hbStop)
+		c.hbStop = nil
+	}
+}
@@ -0,0 +1,126 @@
+package discover
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --------- SIMULATION CLOCKS ---------
+
+// Request/response types for get_time. Time is the pod's simulation clock in
+// seconds.
+type (
+	TimeRequest  struct{}
+	TimeResponse struct {
+		Time float64 `json:"time"`
+	}
+)
+
+// StepTime is the command type for reading a pod's simulation clock.
+const StepTime = "get_time"
+
+func init() {
+	RegisterCommand(StepTime, TimeRequest{}, TimeResponse{})
+}
+
+// PodTime is one clock sample.
+type PodTime struct {
+	SimTime float64       // pod simulation time in seconds
+	Local   time.Time     // local time at the midpoint of the request
+	RTT     time.Duration // round trip of the request
+}
+
+// GetTime reads the pod's simulation clock. The sample is stamped with the
+// local time halfway through the round trip.
+func (c *PodClient) GetTime(ctx context.Context) (PodTime, error) {
+	var resp TimeResponse
+	start := time.Now()
+	if err := c.Call(ctx, StepTime, TimeRequest{}, &resp); err != nil {
+		return PodTime{}, err
+	}
+	rtt := time.Since(start)
+	return PodTime{SimTime: resp.Time, Local: start.Add(rtt / 2), RTT: rtt}, nil
+}
+
+// PodClock is one pod's entry in a ClockSkewReport.
+type PodClock struct {
+	Pod      PodRef
+	Sample   PodTime
+	Skew     time.Duration // ahead (+) or behind (-) the cluster median
+	Drifting bool          // |Skew| > threshold
+	Error    string
+}
+
+// ClockSkewReport compares simulation clocks across configured pods.
+type ClockSkewReport struct {
+	Threshold time.Duration
+	Pods      []PodClock // sorted by pod
+	Drifting  []PodRef
+	MaxSkew   time.Duration // largest |Skew|
+}
+
+// ClockSkewReport samples every configured pod's simulation clock
+// concurrently, projects the samples to a common local instant and reports
+// each pod's offset from the cluster median. Pods further off than threshold
+// are flagged.
+func (d *Discover) ClockSkewReport(ctx context.Context, threshold time.Duration) ClockSkewReport {
+	pods := d.podAddrs()
+	clocks := make([]PodClock, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func(i int, pod PodRef) {
+			defer wg.Done()
+			clocks[i].Pod = pod
+			sample, err := d.Client(pod).GetTime(ctx)
+			if err != nil {
+				clocks[i].Error = err.Error()
+				return
+			}
+			clocks[i].Sample = sample
+		}(i, pod)
+	}
+	wg.Wait()
+
+	// Project each sample to the same instant: sim time at ref = SimTime + (ref - Local).
+	ref := time.Now()
+	var projected []float64
+	for _, c := range clocks {
+		if c.Error == "" {
+			projected = append(projected, c.Sample.SimTime+ref.Sub(c.Sample.Local).Seconds())
+		}
+	}
+	rep := ClockSkewReport{Threshold: threshold}
+	if len(projected) > 0 {
+		median := medianFloat(projected)
+		for i := range clocks {
+			c := &clocks[i]
+			if c.Error != "" {
+				continue
+			}
+			at := c.Sample.SimTime + ref.Sub(c.Sample.Local).Seconds()
+			c.Skew = time.Duration((at - median) * float64(time.Second))
+			abs := c.Skew.Abs()
+			rep.MaxSkew = max(rep.MaxSkew, abs)
+			if threshold > 0 && abs > threshold {
+				c.Drifting = true
+				rep.Drifting = append(rep.Drifting, c.Pod)
+			}
+		}
+	}
+	sort.Slice(clocks, func(i, j int) bool { return clocks[i].Pod.String() < clocks[j].Pod.String() })
+	rep.Pods = clocks
+	return rep
+}
+
+func medianFloat(vs []float64) float64 {
+	s := append([]float64(nil), vs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
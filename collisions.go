@@ -0,0 +1,180 @@
+package discover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --------- PLANET NAME COLLISIONS ---------
+
+// CollisionStrategy decides what happens when two pods report planets with
+// the same name (in Planets, and within a universe in UniversePlanets). Merge
+// uses it too, with the receiver's records as "first".
+type CollisionStrategy int
+
+const (
+	CollideFirstWins CollisionStrategy = iota // keep the planet from the pod listed first (default)
+	CollideLastWins                           // the pod listed last overwrites earlier ones
+	CollideMerge                              // keep the first, filling in missing data and adding locations from the others
+	CollideNamespace                          // key every colliding planet as "host:port/name"
+	CollideError                              // leave the name out; CheckConflicts reports it
+	CollideNewest                             // take the planet from the pod scanned more recently; pods of one scan keep the first
+)
+
+// PlanetConflict is a planet name reported by more than one pod.
+type PlanetConflict struct {
+	Name string
+	Pods []PodRef // in the order the conflict was seen
+}
+
+func (c PlanetConflict) String() string {
+	pods := make([]string, len(c.Pods))
+	for i, p := range c.Pods {
+		pods[i] = p.String()
+	}
+	return fmt.Sprintf("%s on %s", c.Name, strings.Join(pods, ", "))
+}
+
+// PlanetConflictError lists the conflicts of the last scan.
+type PlanetConflictError struct {
+	Conflicts []PlanetConflict
+}
+
+func (e *PlanetConflictError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		parts[i] = c.String()
+	}
+	return fmt.Sprintf("%d planet name conflicts: %s", len(e.Conflicts), strings.Join(parts, "; "))
+}
+
+// flatScope marks the name-keyed Planets map; universe maps use the universe.
+const flatScope = "\x01"
+
+// NamespacedName is the key CollideNamespace stores a colliding planet under.
+func NamespacedName(p PlanetRecord) string {
+	return p.PodRef.String() + "/" + p.Name
+}
+
+// Conflicts returns the planet name collisions seen by the last ScanAll,
+// whatever the strategy, sorted by name.
+func (d *Discover) Conflicts() []PlanetConflict {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]PlanetConflict, len(d.conflicts))
+	for i, c := range d.conflicts {
+		out[i] = PlanetConflict{Name: c.Name, Pods: append([]PodRef(nil), c.Pods...)}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CheckConflicts returns a *PlanetConflictError listing the last scan's
+// collisions, or nil if there were none.
+func (d *Discover) CheckConflicts() error {
+	if c := d.Conflicts(); len(c) > 0 {
+		return &PlanetConflictError{Conflicts: c}
+	}
+	return nil
+}
+
+// placePlanetLocked stores p in m according to Config.Collisions. A planet
+// replacing its own earlier record (same pod) is never a collision. d.mu
+// must be held.
+func (d *Discover) placePlanetLocked(m map[string]PlanetRecord, scope string, p PlanetRecord) {
+	strategy := d.Config.Collisions
+	key := p.Name
+	if d.collided[scope+"\x00"+key] {
+		if strategy == CollideNamespace {
+			m[NamespacedName(p)] = p
+		}
+		if scope == flatScope {
+			d.noteConflictLocked(p.Name, p.PodRef)
+		}
+		return
+	}
+	old, exists := m[key]
+	if !exists || old.PodRef == p.PodRef {
+		m[key] = p
+		return
+	}
+	if scope == flatScope {
+		d.noteConflictLocked(p.Name, old.PodRef)
+		d.noteConflictLocked(p.Name, p.PodRef)
+	}
+	switch strategy {
+	case CollideFirstWins:
+	case CollideLastWins:
+		m[key] = p
+	case CollideMerge:
+		m[key] = mergePlanet(old, p)
+	case CollideNewest:
+		if d.lastSuccessLocked(old.PodRef).Session != d.lastSuccessLocked(p.PodRef).Session {
+			m[key] = p
+		}
+	case CollideNamespace, CollideError:
+		delete(m, key)
+		if strategy == CollideNamespace {
+			m[NamespacedName(old)] = old
+			m[NamespacedName(p)] = p
+		}
+		if d.collided == nil {
+			d.collided = make(map[string]bool)
+		}
+		d.collided[scope+"\x00"+key] = true
+	}
+}
+
+// lastSuccessLocked returns pod's latest successful result. d.mu must be held.
+func (d *Discover) lastSuccessLocked(pod PodRef) PodResult {
+	for i := len(d.Results) - 1; i >= 0; i-- {
+		if r := d.Results[i]; r.Success && r.PodRef == pod {
+			return r
+		}
+	}
+	return PodResult{}
+}
+
+func (d *Discover) noteConflictLocked(name string, pod PodRef) {
+	for i := range d.conflicts {
+		c := &d.conflicts[i]
+		if c.Name != name {
+			continue
+		}
+		if !containsAddr(c.Pods, pod) {
+			c.Pods = append(c.Pods, pod)
+		}
+		return
+	}
+	d.conflicts = append(d.conflicts, PlanetConflict{Name: name, Pods: []PodRef{pod}})
+}
+
+// mergePlanet keeps a's identity and position, takes b's seed and biome if a
+// has none, and adds b's resource and tree locations that a lacks.
+func mergePlanet(a, b PlanetRecord) PlanetRecord {
+	if a.Seed == 0 {
+		a.Seed = b.Seed
+	}
+	if a.BiomeType == 0 {
+		a.BiomeType = b.BiomeType
+	}
+	a.ResourceLocations = unionLocations(a.ResourceLocations, b.ResourceLocations)
+	a.TreeLocations = unionLocations(a.TreeLocations, b.TreeLocations)
+	return a
+}
+
+func unionLocations(a, b [][3]float64) [][3]float64 {
+	seen := make(map[[3]float64]bool, len(a))
+	out := append([][3]float64(nil), a...)
+	for _, l := range a {
+		seen[l] = true
+	}
+	for _, l := range b {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	return out
+}
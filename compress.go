@@ -0,0 +1,111 @@
+package discover
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// --- Payload compression ---
+//
+// When Config.Compression is "gzip", dialPod offers compression right after
+// authenticating:
+//
+//	-> {"type":"negotiate_compression","algorithms":["gzip"]}
+//	<- {"compression":"gzip"}
+//
+// Any other reply keeps the connection uncompressed. Once agreed, every
+// message in both directions is gzipped. Length-prefixed framing carries the
+// bytes as-is; delimiter framing and WebSocket text frames carry them
+// base64-encoded so the delimiter can't appear inside a payload.
+
+// CompressionGzip is the only compression algorithm currently offered.
+const CompressionGzip = "gzip"
+
+// binarySafe is implemented by transports that can carry arbitrary bytes.
+type binarySafe interface {
+	binarySafe() bool
+}
+
+func (c *podConn) binarySafe() bool { return c.framing == FramingLengthPrefix }
+
+// A gzip transport carries arbitrary bytes, base64-wrapping them if needed.
+func (g *gzipTransport) binarySafe() bool { return true }
+
+type gzipTransport struct {
+	inner  msgTransport
+	base64 bool
+}
+
+// negotiateCompression offers the configured algorithm and wraps pc if the pod accepts.
+func negotiateCompression(pc msgTransport, algorithm string) (msgTransport, error) {
+	offer, _ := json.Marshal(map[string]any{
+		"type":       "negotiate_compression",
+		"algorithms": []string{algorithm},
+	})
+	if err := pc.sendMsg(string(offer)); err != nil {
+		return nil, err
+	}
+	resp, err := pc.readMsg()
+	if err != nil {
+		return nil, err
+	}
+	var reply struct {
+		Compression string `json:"compression"`
+	}
+	if json.Unmarshal([]byte(resp), &reply) != nil || reply.Compression != algorithm {
+		return pc, nil
+	}
+	bs, ok := pc.(binarySafe)
+	return &gzipTransport{inner: pc, base64: !ok || !bs.binarySafe()}, nil
+}
+
+func (g *gzipTransport) sendMsg(msg string) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, msg); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if g.base64 {
+		return g.inner.sendMsg(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	return g.inner.sendMsg(buf.String())
+}
+
+func (g *gzipTransport) readMsg() (string, error) {
+	raw, err := g.inner.readMsg()
+	if err != nil {
+		return "", err
+	}
+	data := []byte(raw)
+	if g.base64 {
+		if data, err = base64.StdEncoding.DecodeString(raw); err != nil {
+			return "", err
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, maxFrameSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(out) > maxFrameSize {
+		return "", errFrameTooLarge
+	}
+	return string(out), nil
+}
+
+func (g *gzipTransport) setTimeout(d time.Duration) { g.inner.setTimeout(d) }
+
+func (g *gzipTransport) Close() error {
+	return g.inner.Close()
+}
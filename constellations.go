@@ -0,0 +1,158 @@
+package discover
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --------- CONSTELLATIONS ---------
+
+// Constellations are curated, named groups of planets. Definitions are saved
+// in the StateStore under "constellations/<name>", so they survive restarts
+// (call LoadConstellations on startup) and are shared by processes using the
+// same store.
+
+const constellationPrefix = "constellations/"
+
+// DefineConstellation creates or replaces a constellation. Planets don't have
+// to be discovered yet; queries only use the ones that are.
+func (d *Discover) DefineConstellation(name string, planetNames []string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid constellation name %q", name)
+	}
+	members := uniqueSorted(planetNames)
+	data, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	if err := d.store().Put(constellationPrefix+name, data); err != nil {
+		return fmt.Errorf("constellation %s: %w", name, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.constellations == nil {
+		d.constellations = make(map[string][]string)
+	}
+	d.constellations[name] = members
+	return nil
+}
+
+// RemoveConstellation deletes a constellation and its saved definition.
+func (d *Discover) RemoveConstellation(name string) error {
+	if err := d.store().Delete(constellationPrefix + name); err != nil {
+		return fmt.Errorf("constellation %s: %w", name, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.constellations, name)
+	return nil
+}
+
+// LoadConstellations replaces the in-memory constellations with the ones
+// saved in the store.
+func (d *Discover) LoadConstellations() error {
+	st := d.store()
+	keys, err := st.List(constellationPrefix)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string][]string, len(keys))
+	for _, key := range keys {
+		data, err := st.Get(key)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		var members []string
+		if err := json.Unmarshal(data, &members); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		loaded[strings.TrimPrefix(key, constellationPrefix)] = members
+	}
+	d.mu.Lock()
+	d.constellations = loaded
+	d.mu.Unlock()
+	return nil
+}
+
+// Constellations lists the defined constellation names, sorted.
+func (d *Discover) Constellations() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.constellations))
+	for name := range d.constellations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConstellationPlanets returns the planet names in a constellation.
+func (d *Discover) ConstellationPlanets(name string) ([]string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	members, ok := d.constellations[name]
+	return append([]string(nil), members...), ok
+}
+
+// ConstellationOf returns the constellations a planet belongs to, sorted.
+func (d *Discover) ConstellationOf(planet string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []string
+	for name, members := range d.constellations {
+		if i := sort.SearchStrings(members, planet); i < len(members) && members[i] == planet {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ConstellationBounds returns the axis-aligned box around the constellation's
+// discovered planets.
+func (d *Discover) ConstellationBounds(name string) (lo, hi [3]float64, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	members, ok := d.constellations[name]
+	if !ok {
+		return lo, hi, fmt.Errorf("constellation %s not found", name)
+	}
+	found := false
+	for _, pn := range members {
+		p, ok := d.Planets[pn]
+		if !ok {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if !found || p.Coordinates[i] < lo[i] {
+				lo[i] = p.Coordinates[i]
+			}
+			if !found || p.Coordinates[i] > hi[i] {
+				hi[i] = p.Coordinates[i]
+			}
+		}
+		found = true
+	}
+	if !found {
+		return lo, hi, fmt.Errorf("constellation %s has no discovered planets", name)
+	}
+	return lo, hi, nil
+}
+
+func uniqueSorted(in []string) []string {
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	kept := out[:0]
+	for i, s := range out {
+		if i == 0 || s != out[i-1] {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
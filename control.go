@@ -0,0 +1,520 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// --------- CUBE CONTROL ---------
+
+// Pod commands that create and change cubes. Vectors are {"x","y","z"} maps
+// like get_cube_position's; rotations are Euler angles in degrees. The pod
+// answers {"ok":true} or {"error":"..."}.
+//
+//	-> {"type":"spawn_cube","cube_name":"...","position":{...},"rotation":{...}}
+//	-> {"type":"despawn_cube","cube_name":"..."}
+//	-> {"type":"move_cube","cube_name":"...","position":{...},"rotation":{...}}
+//	-> {"type":"freeze_cube","cube_name":"..."}
+//	-> {"type":"unfreeze_cube","cube_name":"..."}
+//	-> {"type":"apply_force","cube_name":"...","force":{...}}
+//	-> {"type":"apply_impulse","cube_name":"...","impulse":{...}}
+//	-> {"type":"get_cube_state","cube_name":"..."}
+//	<- {"cube_name":"...","position":{...},"rotation":{...},"velocity":{...},...}
+const (
+	CmdSpawnCube    = "spawn_cube"
+	CmdDespawnCube  = "despawn_cube"
+	CmdMoveCube     = "move_cube"
+	CmdFreezeCube   = "freeze_cube"
+	CmdUnfreezeCube = "unfreeze_cube"
+	CmdApplyForce   = "apply_force"
+	CmdApplyImpulse = "apply_impulse"
+	CmdCubeState    = "get_cube_state"
+)
+
+type (
+	SpawnCubeRequest struct {
+		CubeName string             `json:"cube_name"`
+		Position map[string]float64 `json:"position"`
+		Rotation map[string]float64 `json:"rotation,omitempty"` // omitted = the pod's default
+	}
+	DespawnCubeRequest struct {
+		CubeName string `json:"cube_name"`
+	}
+	MoveCubeRequest struct {
+		CubeName string             `json:"cube_name"`
+		Position map[string]float64 `json:"position"`
+		Rotation map[string]float64 `json:"rotation,omitempty"` // omitted = keep the current rotation
+	}
+	FreezeCubeRequest struct {
+		CubeName string `json:"cube_name"`
+	}
+	ApplyForceRequest struct {
+		CubeName string             `json:"cube_name"`
+		Force    map[string]float64 `json:"force"`
+	}
+	ApplyImpulseRequest struct {
+		CubeName string             `json:"cube_name"`
+		Impulse  map[string]float64 `json:"impulse"`
+	}
+	CubeStateRequest struct {
+		CubeName string `json:"cube_name"`
+	}
+	AckResponse struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+)
+
+func init() {
+	RegisterCommand(CmdSpawnCube, SpawnCubeRequest{}, AckResponse{})
+	RegisterCommand(CmdDespawnCube, DespawnCubeRequest{}, AckResponse{})
+	RegisterCommand(CmdMoveCube, MoveCubeRequest{}, AckResponse{})
+	RegisterCommand(CmdFreezeCube, FreezeCubeRequest{}, AckResponse{})
+	RegisterCommand(CmdUnfreezeCube, FreezeCubeRequest{}, AckResponse{})
+	RegisterCommand(CmdApplyForce, ApplyForceRequest{}, AckResponse{})
+	RegisterCommand(CmdApplyImpulse, ApplyImpulseRequest{}, AckResponse{})
+	RegisterCommand(CmdCubeState, CubeStateRequest{}, CubeState{})
+}
+
+// ErrNotAcknowledged is returned when a pod answers a control command without
+// {"ok":true}, e.g. {"ok":false} or an unknown-command reply.
+var ErrNotAcknowledged = errors.New("not acknowledged")
+
+// Validate turns an {"error":...} reply, or one without "ok":true, into an
+// error.
+func (r *AckResponse) Validate() error {
+	if r.Error != "" {
+		return fmt.Errorf("pod refused: %s", r.Error)
+	}
+	if !r.OK {
+		return ErrNotAcknowledged
+	}
+	return nil
+}
+
+// xyz converts a vector to the pods' {"x","y","z"} form; nil stays nil.
+func xyz(v []float64) map[string]float64 {
+	if v == nil {
+		return nil
+	}
+	p := Vec3Of(v)
+	return map[string]float64{"x": p[0], "y": p[1], "z": p[2]}
+}
+
+// SpawnCube creates a cube named name at pos with rotation rot (nil for the
+// pod's default).
+func (c *PodClient) SpawnCube(name string, pos []float64, rot []float64) error {
+	return c.SpawnCubeContext(context.Background(), name, pos, rot)
+}
+
+// SpawnCubeContext is SpawnCube with a context for cancellation.
+func (c *PodClient) SpawnCubeContext(ctx context.Context, name string, pos []float64, rot []float64) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdSpawnCube, SpawnCubeRequest{CubeName: name, Position: xyz(pos), Rotation: xyz(rot)}, &resp)
+}
+
+// SpawnCubeOn spawns a cube on the pod that hosts planetName, e.g. at a
+// position from GenerateSpawnPositions, and records it in Cubes so lookups
+// work before the next scan.
+func (d *Discover) SpawnCubeOn(planetName, cubeName string, pos, rot []float64) error {
+	planet, ok := d.LookupPlanet(planetName)
+	if !ok {
+		return fmt.Errorf("planet %s not found", planetName)
+	}
+	if err := d.spawnOn(planet.PodRef, planetName, cubeName, pos, rot); err != nil {
+		return fmt.Errorf("cube %s spawn on %s: %w", cubeName, planet.PodRef, err)
+	}
+	return nil
+}
+
+// spawnOn spawns a cube on pod and records it in Cubes and the unit registry.
+func (d *Discover) spawnOn(pod PodRef, planetName, cubeName string, pos, rot []float64) error {
+	if err := d.Client(pod).SpawnCube(cubeName, pos, rot); err != nil {
+		return err
+	}
+	d.units.spawned(cubeName, pod, planetName, pos)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.Cubes == nil {
+		d.Cubes = make(map[string]CubeRecord)
+	}
+	d.Cubes[cubeName] = CubeRecord{Name: cubeName, PodRef: pod}
+	return nil
+}
+
+// DespawnCube removes the named cube from the pod.
+func (c *PodClient) DespawnCube(name string) error {
+	return c.DespawnCubeContext(context.Background(), name)
+}
+
+// DespawnCubeContext is DespawnCube with a context for cancellation.
+func (c *PodClient) DespawnCubeContext(ctx context.Context, name string) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdDespawnCube, DespawnCubeRequest{CubeName: name}, &resp)
+}
+
+// cubePods returns every pod known to host cubeName: the Cubes entry and any
+// pod whose latest successful scan listed it, sorted. Older scans don't
+// count, so a cube that moved is only found where it is now.
+func (d *Discover) cubePods(cubeName string) []PodRef {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seen := make(map[PodRef]bool)
+	if c, ok := d.Cubes[cubeName]; ok {
+		seen[c.PodRef] = true
+	}
+	for pod, res := range latestResults(d.Results) {
+		if slices.Contains(res.Cubes, cubeName) {
+			seen[pod] = true
+		}
+	}
+	pods := make([]PodRef, 0, len(seen))
+	for p := range seen {
+		pods = append(pods, p)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Host != pods[j].Host {
+			return pods[i].Host < pods[j].Host
+		}
+		return pods[i].Port < pods[j].Port
+	})
+	return pods
+}
+
+// DespawnEverywhere removes cubeName from every pod known to host it and
+// forgets it locally on those that succeed. It returns the pods it was
+// removed from; failures on other pods are joined into the error.
+func (d *Discover) DespawnEverywhere(cubeName string) ([]PodRef, error) {
+	release, err := d.units.reserve(cubeName, "despawning")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	pods := d.cubePods(cubeName)
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("cube %s not found", cubeName)
+	}
+	var removed []PodRef
+	var errs []error
+	for _, pod := range pods {
+		if err := d.Client(pod).DespawnCube(cubeName); err != nil {
+			errs = append(errs, fmt.Errorf("cube %s despawn on %s: %w", cubeName, pod, err))
+			continue
+		}
+		removed = append(removed, pod)
+		d.forgetCube(cubeName, pod)
+		d.units.despawned(cubeName, pod)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// forgetCube drops cubeName from Cubes and the scan results of pod.
+func (d *Discover) forgetCube(cubeName string, pod PodRef) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forgetCubeLocked(cubeName, pod)
+}
+
+// forgetCubeLocked is forgetCube with d.mu held.
+func (d *Discover) forgetCubeLocked(cubeName string, pod PodRef) {
+	if c, ok := d.Cubes[cubeName]; ok && c.PodRef == pod {
+		delete(d.Cubes, cubeName)
+	}
+	for i, res := range d.Results {
+		if res.PodRef == pod && slices.Contains(res.Cubes, cubeName) {
+			d.Results[i].Cubes = slices.DeleteFunc(slices.Clone(res.Cubes), func(c string) bool { return c == cubeName })
+		}
+	}
+}
+
+// MoveCube teleports the named cube to pos with rotation rot (nil keeps the
+// current rotation). It does no checks; see Discover.MoveCube.
+func (c *PodClient) MoveCube(name string, pos, rot []float64) error {
+	return c.MoveCubeContext(context.Background(), name, pos, rot)
+}
+
+// MoveCubeContext is MoveCube with a context for cancellation.
+func (c *PodClient) MoveCubeContext(ctx context.Context, name string, pos, rot []float64) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdMoveCube, MoveCubeRequest{CubeName: name, Position: xyz(pos), Rotation: xyz(rot)}, &resp)
+}
+
+// hostPod returns the single pod hosting cubeName.
+func (d *Discover) hostPod(cubeName string) (PodRef, error) {
+	switch pods := d.cubePods(cubeName); len(pods) {
+	case 0:
+		return PodRef{}, fmt.Errorf("cube %s not found", cubeName)
+	case 1:
+		return pods[0], nil
+	default:
+		return PodRef{}, fmt.Errorf("cube %s is on several pods %v", cubeName, pods)
+	}
+}
+
+// DefaultMoveClearance is the distance from every planet MoveCube requires
+// when Config.MoveClearance is 0.
+const DefaultMoveClearance = 50.0
+
+// MoveCube moves a cube on the pod hosting it, after checking that the
+// destination is a finite 3-vector, at least Config.MoveClearance from every
+// planet (DefaultMoveClearance when unset) and inside the pod's
+// Config.WorldBounds entry, if it has one.
+func (d *Discover) MoveCube(cubeName string, newPos, newRot []float64) error {
+	if !validVec3(newPos) {
+		return fmt.Errorf("cube %s move: bad position %v", cubeName, newPos)
+	}
+	if newRot != nil && !validVec3(newRot) {
+		return fmt.Errorf("cube %s move: bad rotation %v", cubeName, newRot)
+	}
+	release, err := d.units.reserve(cubeName, "moving")
+	if err != nil {
+		return err
+	}
+	defer release()
+	pod, err := d.hostPod(cubeName)
+	if err != nil {
+		return err
+	}
+	if c := d.moveClearance(); c > 0 && !d.IsSpawnPointFree(newPos, c) {
+		return fmt.Errorf("cube %s move: %v is within %g of a planet", cubeName, newPos, c)
+	}
+	if b, ok := d.Config.WorldBounds[pod]; ok {
+		p := Vec3Of(newPos)
+		for a := range p {
+			if p[a] < b.Min[a] || p[a] > b.Max[a] {
+				return fmt.Errorf("cube %s move: %v is outside the world bounds of %s", cubeName, newPos, pod)
+			}
+		}
+	}
+	if err := d.Client(pod).MoveCube(cubeName, newPos, newRot); err != nil {
+		return fmt.Errorf("cube %s move on %s: %w", cubeName, pod, err)
+	}
+	d.units.moved(cubeName, newPos)
+	return nil
+}
+
+func (d *Discover) moveClearance() float64 {
+	if d.Config.MoveClearance == 0 {
+		return DefaultMoveClearance
+	}
+	return d.Config.MoveClearance
+}
+
+// validVec3 reports whether v has exactly three finite components.
+func validVec3(v []float64) bool {
+	return len(v) == 3 && finite3(vec3f(v))
+}
+
+// --- freezing ---
+
+// FreezeCube stops physics for the named cube; it holds still until
+// UnfreezeCube.
+func (c *PodClient) FreezeCube(name string) error {
+	return c.FreezeCubeContext(context.Background(), name)
+}
+
+// FreezeCubeContext is FreezeCube with a context for cancellation.
+func (c *PodClient) FreezeCubeContext(ctx context.Context, name string) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdFreezeCube, FreezeCubeRequest{CubeName: name}, &resp)
+}
+
+// UnfreezeCube resumes physics for the named cube.
+func (c *PodClient) UnfreezeCube(name string) error {
+	return c.UnfreezeCubeContext(context.Background(), name)
+}
+
+// UnfreezeCubeContext is UnfreezeCube with a context for cancellation.
+func (c *PodClient) UnfreezeCubeContext(ctx context.Context, name string) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdUnfreezeCube, FreezeCubeRequest{CubeName: name}, &resp)
+}
+
+// FreezeCubes freezes each named cube on the pod hosting it, e.g. while a
+// formation is being staged. Failures are joined into the error.
+func (d *Discover) FreezeCubes(names ...string) error {
+	return d.eachCube(names, CmdFreezeCube)
+}
+
+// UnfreezeCubes unfreezes the named cubes. The commands are sent
+// concurrently so a staged formation is released together.
+func (d *Discover) UnfreezeCubes(names ...string) error {
+	return d.eachCube(names, CmdUnfreezeCube)
+}
+
+// eachCube sends a freeze-style command for every cube concurrently.
+func (d *Discover) eachCube(names []string, cmdType string) error {
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pod, err := d.hostPod(name)
+			if err == nil {
+				var resp AckResponse
+				if err = d.Client(pod).Call(context.Background(), cmdType, FreezeCubeRequest{CubeName: name}, &resp); err != nil {
+					err = fmt.Errorf("cube %s %s on %s: %w", name, cmdType, pod, err)
+				}
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// --- forces ---
+
+// ApplyForce pushes the named cube with a continuous force for the pod's
+// next physics step.
+func (c *PodClient) ApplyForce(name string, force []float64) error {
+	return c.ApplyForceContext(context.Background(), name, force)
+}
+
+// ApplyForceContext is ApplyForce with a context for cancellation.
+func (c *PodClient) ApplyForceContext(ctx context.Context, name string, force []float64) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdApplyForce, ApplyForceRequest{CubeName: name, Force: xyz(force)}, &resp)
+}
+
+// ApplyImpulse gives the named cube an instant change in momentum.
+func (c *PodClient) ApplyImpulse(name string, impulse []float64) error {
+	return c.ApplyImpulseContext(context.Background(), name, impulse)
+}
+
+// ApplyImpulseContext is ApplyImpulse with a context for cancellation.
+func (c *PodClient) ApplyImpulseContext(ctx context.Context, name string, impulse []float64) error {
+	var resp AckResponse
+	return c.Call(ctx, CmdApplyImpulse, ApplyImpulseRequest{CubeName: name, Impulse: xyz(impulse)}, &resp)
+}
+
+// ApplyForce sends apply_force to the pod hosting the cube.
+func (d *Discover) ApplyForce(cubeName string, force []float64) error {
+	return d.push(cubeName, CmdApplyForce, force)
+}
+
+// ApplyImpulse sends apply_impulse to the pod hosting the cube.
+func (d *Discover) ApplyImpulse(cubeName string, impulse []float64) error {
+	return d.push(cubeName, CmdApplyImpulse, impulse)
+}
+
+func (d *Discover) push(cubeName, cmdType string, v []float64) error {
+	if !validVec3(v) {
+		return fmt.Errorf("cube %s %s: bad vector %v", cubeName, cmdType, v)
+	}
+	pod, err := d.hostPod(cubeName)
+	if err != nil {
+		return err
+	}
+	c := d.Client(pod)
+	if cmdType == CmdApplyForce {
+		err = c.ApplyForce(cubeName, v)
+	} else {
+		err = c.ApplyImpulse(cubeName, v)
+	}
+	if err != nil {
+		return fmt.Errorf("cube %s %s on %s: %w", cubeName, cmdType, pod, err)
+	}
+	return nil
+}
+
+// --- cube state ---
+
+// CubeState is a cube as its pod reports it.
+type CubeState struct {
+	Name     string
+	Position Vec3
+	Rotation Vec3                       // Euler angles in degrees
+	Velocity Vec3                       // zero if the pod doesn't report it
+	Metadata map[string]json.RawMessage // every other reply field, e.g. "hp"
+	err      string                     // the reply's "error", see Validate
+	noPos    bool
+	noRot    bool
+}
+
+var cubeStateFields = map[string]bool{"type": true, "cube_name": true, "position": true, "rotation": true, "velocity": true, "error": true}
+
+// UnmarshalJSON decodes a get_cube_state reply, keeping unknown fields in
+// Metadata.
+func (s *CubeState) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	var raw struct {
+		CubeName string             `json:"cube_name"`
+		Position map[string]float64 `json:"position"`
+		Rotation map[string]float64 `json:"rotation"`
+		Velocity map[string]float64 `json:"velocity"`
+		Error    string             `json:"error"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = CubeState{Name: raw.CubeName, Position: vec3(raw.Position), Rotation: vec3(raw.Rotation), Velocity: vec3(raw.Velocity), err: raw.Error, noPos: raw.Position == nil, noRot: raw.Rotation == nil}
+	for k, v := range fields {
+		if !cubeStateFields[k] {
+			if s.Metadata == nil {
+				s.Metadata = make(map[string]json.RawMessage)
+			}
+			s.Metadata[k] = v
+		}
+	}
+	return nil
+}
+
+// HasRotation reports whether the pod included a rotation; Rotation is zero
+// when it didn't.
+func (s CubeState) HasRotation() bool { return !s.noRot }
+
+// Validate reports an {"error":...} reply or one without a position.
+func (s *CubeState) Validate() error {
+	if s.err != "" {
+		return fmt.Errorf("pod refused: %s", s.err)
+	}
+	if s.noPos {
+		return fmt.Errorf("no position in reply")
+	}
+	return nil
+}
+
+// GetCubeState asks the pod for a cube's position, rotation, velocity and
+// any other state it reports.
+func (c *PodClient) GetCubeState(name string) (CubeState, error) {
+	return c.GetCubeStateContext(context.Background(), name)
+}
+
+// GetCubeStateContext is GetCubeState with a context for cancellation.
+func (c *PodClient) GetCubeStateContext(ctx context.Context, name string) (CubeState, error) {
+	var s CubeState
+	err := c.Call(ctx, CmdCubeState, CubeStateRequest{CubeName: name}, &s)
+	if err == nil && s.Name == "" {
+		s.Name = name
+	}
+	return s, err
+}
+
+// GetCubeState reads a cube's state from the pod hosting it, for monitoring
+// individual units.
+func (d *Discover) GetCubeState(cubeName string) (CubeState, error) {
+	return d.GetCubeStateContext(context.Background(), cubeName)
+}
+
+// GetCubeStateContext is GetCubeState with a context for cancellation.
+func (d *Discover) GetCubeStateContext(ctx context.Context, cubeName string) (CubeState, error) {
+	pod, err := d.hostPod(cubeName)
+	if err != nil {
+		return CubeState{}, err
+	}
+	s, err := d.Client(pod).GetCubeStateContext(ctx, cubeName)
+	if err != nil {
+		return CubeState{}, fmt.Errorf("cube %s state on %s: %w", cubeName, pod, err)
+	}
+	return s, nil
+}
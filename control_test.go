@@ -0,0 +1,121 @@
+package discover
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testDelim = "<???DONE???---"
+
+// fakePod answers auth and get_cube_list with whatever cubes it holds.
+type fakePod struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	cubes []string
+}
+
+func (p *fakePod) setCubes(cubes ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cubes = cubes
+}
+
+func (p *fakePod) serve() {
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				var msg strings.Builder
+				for !strings.HasSuffix(msg.String(), testDelim) {
+					b, err := r.ReadByte()
+					if err != nil {
+						return
+					}
+					msg.WriteByte(b)
+				}
+				reply := `{}`
+				switch m := msg.String(); {
+				case strings.HasPrefix(m, "pw"):
+					reply = `{"type":"auth_success"}`
+				case strings.Contains(m, "get_cube_list"):
+					p.mu.Lock()
+					reply = `{"type":"cube_list","cubes":["` + strings.Join(p.cubes, `","`) + `"]}`
+					if len(p.cubes) == 0 {
+						reply = `{"type":"cube_list","cubes":[]}`
+					}
+					p.mu.Unlock()
+				}
+				conn.Write([]byte(reply + testDelim))
+			}
+		}()
+	}
+}
+
+// fakePods starts n fake pods on consecutive ports of 127.0.0.1.
+func fakePods(t *testing.T, n int) ([]*fakePod, Config) {
+	t.Helper()
+	for attempt := 0; attempt < 20; attempt++ {
+		first, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := first.Addr().(*net.TCPAddr).Port
+		pods := []*fakePod{{ln: first}}
+		for i := 1; i < n; i++ {
+			ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port+i)))
+			if err != nil {
+				break
+			}
+			pods = append(pods, &fakePod{ln: ln})
+		}
+		if len(pods) < n {
+			for _, p := range pods {
+				p.ln.Close()
+			}
+			continue
+		}
+		for _, p := range pods {
+			go p.serve()
+			t.Cleanup(func() { p.ln.Close() })
+		}
+		cfg := Config{Hosts: []string{"127.0.0.1"}, StartPort: port, PortStep: 1, NumPods: n, AuthPass: "pw", Delimiter: testDelim, TimeoutSec: 2}
+		return pods, cfg
+	}
+	t.Fatal("no consecutive free ports")
+	return nil, Config{}
+}
+
+func TestCubePodsFollowsMovedCube(t *testing.T) {
+	pods, cfg := fakePods(t, 2)
+	d := NewDiscover(cfg)
+	podA := PodRef{Host: "127.0.0.1", Port: cfg.StartPort}
+	podB := PodRef{Host: "127.0.0.1", Port: cfg.StartPort + 1}
+
+	pods[0].setCubes("c1")
+	d.ScanAll()
+	if got, err := d.hostPod("c1"); err != nil || got != podA {
+		t.Fatalf("after first scan: hostPod = %v, %v; want %v", got, err, podA)
+	}
+
+	pods[0].setCubes()
+	pods[1].setCubes("c1")
+	d.ScanAll()
+	if got := d.cubePods("c1"); len(got) != 1 || got[0] != podB {
+		t.Fatalf("after second scan: cubePods = %v; want [%v]", got, podB)
+	}
+	if got, err := d.hostPod("c1"); err != nil || got != podB {
+		t.Fatalf("after second scan: hostPod = %v, %v; want %v", got, err, podB)
+	}
+	if got, err := d.cubePod("c1"); err != nil || got != podB {
+		t.Fatalf("after second scan: cubePod = %v, %v; want %v", got, err, podB)
+	}
+}
@@ -0,0 +1,87 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+)
+
+// --------- COORDINATE TRANSFORMS ---------
+
+// GeoCoord is a position relative to a planet: latitude and longitude in
+// degrees and altitude above the surface. Latitude is positive toward the
+// planet's +Y pole; longitude is 0 on +X and grows eastward (toward -Z, the
+// East of TangentBasis), in (-180, 180].
+type GeoCoord struct {
+	Lat, Lon, Alt float64
+}
+
+// PlanetCoords converts between world coordinates, the planet-local frame and
+// GeoCoords for one planet. The local frame has its origin at the planet's
+// center and axes given by Orientation (the identity for world-aligned).
+type PlanetCoords struct {
+	Center      [3]float64
+	Radius      float64 // surface radius; 0 makes Alt the distance from Center
+	Orientation Matrix3 // local axes as columns, in world coordinates
+}
+
+// NewPlanetCoords returns world-aligned PlanetCoords for a sphere.
+func NewPlanetCoords(center []float64, radius float64) PlanetCoords {
+	return PlanetCoords{Center: vec3f(center), Radius: radius, Orientation: IdentityQuaternion.Matrix()}
+}
+
+// PlanetCoords returns the coordinate toolkit for a planet, with its radius
+// from PlanetRadius (0 if it has none).
+func (d *Discover) PlanetCoords(name string) (PlanetCoords, error) {
+	p, ok := d.LookupPlanet(name)
+	if !ok {
+		return PlanetCoords{}, fmt.Errorf("planet %s not found", name)
+	}
+	r, _ := d.PlanetRadius(name)
+	return NewPlanetCoords(p.Coordinates[:], r), nil
+}
+
+// ToLocal converts a world position to the planet-local frame.
+func (c PlanetCoords) ToLocal(world []float64) []float64 {
+	v := sub3(vec3f(world), c.Center)
+	m := c.Orientation
+	return []float64{
+		m[0][0]*v[0] + m[1][0]*v[1] + m[2][0]*v[2],
+		m[0][1]*v[0] + m[1][1]*v[1] + m[2][1]*v[2],
+		m[0][2]*v[0] + m[1][2]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// ToWorld converts a planet-local position to world coordinates.
+func (c PlanetCoords) ToWorld(local []float64) []float64 {
+	v := c.Orientation.Rotate(local)
+	return []float64{v[0] + c.Center[0], v[1] + c.Center[1], v[2] + c.Center[2]}
+}
+
+// ToGeo converts a world position to latitude, longitude and altitude. The
+// center itself maps to latitude and longitude 0.
+func (c PlanetCoords) ToGeo(world []float64) GeoCoord {
+	const deg = 180 / math.Pi
+	l := c.ToLocal(world)
+	r := math.Sqrt(l[0]*l[0] + l[1]*l[1] + l[2]*l[2])
+	g := GeoCoord{Alt: r - c.Radius}
+	if r > 0 {
+		g.Lat = math.Asin(math.Max(-1, math.Min(1, l[1]/r))) * deg
+		g.Lon = math.Atan2(-l[2], l[0]) * deg
+	}
+	switch g.Lon {
+	case -180:
+		g.Lon = 180
+	case 0:
+		g.Lon = 0 // no -0
+	}
+	return g
+}
+
+// FromGeo converts latitude, longitude and altitude to a world position.
+func (c PlanetCoords) FromGeo(g GeoCoord) []float64 {
+	const rad = math.Pi / 180
+	r := c.Radius + g.Alt
+	sinLat, cosLat := math.Sincos(g.Lat * rad)
+	sinLon, cosLon := math.Sincos(g.Lon * rad)
+	return c.ToWorld([]float64{r * cosLat * cosLon, r * sinLat, -r * cosLat * sinLon})
+}
@@ -0,0 +1,79 @@
+package discover
+
+import "sort"
+
+// --------- COVERAGE ---------
+
+// Region is the axis-aligned box spanned by the planets one pod last reported.
+type Region struct {
+	Pod     PodRef
+	Min     [3]float64
+	Max     [3]float64
+	Planets []string
+}
+
+// CoverageReport summarizes how complete the current world view is.
+type CoverageReport struct {
+	Configured int     // pods the config (and probe) expect
+	Responded  int     // pods whose latest scan succeeded
+	Fraction   float64 // Responded / Configured; 1 when nothing is configured
+	FailedPods []PodRef
+	// NeverSeen are failed pods that have never answered, so what they host is unknown.
+	NeverSeen []PodRef
+	// PossiblyMissing lists planets last reported by pods that failed their
+	// latest scan, with the region each of those pods covered.
+	PossiblyMissing []string
+	MissingRegions  []Region
+}
+
+// Coverage compares the latest result of every configured pod with what it
+// reported when it last succeeded, so callers can decide whether a partial
+// discovery is good enough.
+func (d *Discover) Coverage() CoverageReport {
+	pods := d.podAddrs()
+	d.mu.Lock()
+	latest := map[PodRef]PodResult{}
+	lastGood := map[PodRef]PodResult{}
+	for _, res := range d.Results {
+		latest[res.PodRef] = res
+		if res.Success {
+			lastGood[res.PodRef] = res
+		}
+	}
+	d.mu.Unlock()
+
+	rep := CoverageReport{Configured: len(pods)}
+	for _, pod := range pods {
+		if res, ok := latest[pod]; ok && res.Success {
+			rep.Responded++
+			continue
+		}
+		rep.FailedPods = append(rep.FailedPods, pod)
+		good, ok := lastGood[pod]
+		if !ok {
+			rep.NeverSeen = append(rep.NeverSeen, pod)
+			continue
+		}
+		if len(good.Planets) == 0 {
+			continue
+		}
+		region := Region{Pod: pod, Min: good.Planets[0].Coordinates, Max: good.Planets[0].Coordinates}
+		for _, p := range good.Planets {
+			for i := 0; i < 3; i++ {
+				region.Min[i] = min(region.Min[i], p.Coordinates[i])
+				region.Max[i] = max(region.Max[i], p.Coordinates[i])
+			}
+			region.Planets = append(region.Planets, p.Name)
+			rep.PossiblyMissing = append(rep.PossiblyMissing, p.Name)
+		}
+		sort.Strings(region.Planets)
+		rep.MissingRegions = append(rep.MissingRegions, region)
+	}
+	sort.Strings(rep.PossiblyMissing)
+
+	rep.Fraction = 1
+	if rep.Configured > 0 {
+		rep.Fraction = float64(rep.Responded) / float64(rep.Configured)
+	}
+	return rep
+}
@@ -0,0 +1,132 @@
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// --------- CUBE LOOKUPS ---------
+
+// CubeRecord is a discovered cube and the pod hosting it. State holds the
+// pod's description of the cube when get_cube_list lists cubes as objects
+// ({"name":"c1","hp":80,...}) rather than bare names; it is nil otherwise.
+type CubeRecord struct {
+	Name string
+	PodRef
+	State json.RawMessage
+}
+
+func (c CubeRecord) String() string {
+	return fmt.Sprintf("%s on %s", c.Name, c.PodRef)
+}
+
+// UnmarshalJSON accepts cube lists of names, objects, or a mix. An object's
+// name is read from "name", "Name" or "cube_name" and the whole object is
+// kept in State.
+func (r *CubeListResponse) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Cubes []json.RawMessage `json:"cubes"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	r.Cubes, r.State = nil, nil
+	for _, entry := range raw.Cubes {
+		var name string
+		if json.Unmarshal(entry, &name) == nil {
+			r.Cubes = append(r.Cubes, name)
+			continue
+		}
+		var obj struct {
+			Name     string `json:"name"`
+			CubeName string `json:"cube_name"`
+		}
+		if err := json.Unmarshal(entry, &obj); err != nil {
+			return fmt.Errorf("cube entry %s: %w", entry, err)
+		}
+		if obj.Name == "" {
+			obj.Name = obj.CubeName
+		}
+		if obj.Name == "" {
+			return fmt.Errorf("cube entry %s has no name", entry)
+		}
+		r.Cubes = append(r.Cubes, obj.Name)
+		if r.State == nil {
+			r.State = make(map[string]json.RawMessage)
+		}
+		r.State[obj.Name] = entry
+	}
+	return nil
+}
+
+// cubePod returns the pod hosting cubeName: its Cubes entry, which follows
+// spawns, merges and snapshots, or else the newest successful scan listing it.
+func (d *Discover) cubePod(cubeName string) (PodRef, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok := d.Cubes[cubeName]; ok {
+		return c.PodRef, nil
+	}
+	var found *PodResult
+	for _, res := range latestResults(d.Results) {
+		if slices.Contains(res.Cubes, cubeName) && (found == nil || res.ScannedAt.After(found.ScannedAt)) {
+			found = &res
+		}
+	}
+	if found == nil {
+		return PodRef{}, fmt.Errorf("cube %s not found", cubeName)
+	}
+	return found.PodRef, nil
+}
+
+// latestResults maps each pod to its newest successful result; results
+// appended later win ties.
+func latestResults(results []PodResult) map[PodRef]PodResult {
+	out := make(map[PodRef]PodResult)
+	for _, r := range results {
+		if prev, ok := out[r.PodRef]; r.Success && (!ok || !r.ScannedAt.Before(prev.ScannedAt)) {
+			out[r.PodRef] = r
+		}
+	}
+	return out
+}
+
+// CubePosition asks the pod hosting cubeName for the cube's current position.
+func (d *Discover) CubePosition(cubeName string) ([]float64, error) {
+	pod, err := d.cubePod(cubeName)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := sendPodCommand(d.Config, pod, map[string]string{
+		"type":      "get_cube_position",
+		"cube_name": cubeName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cube %s position: %w", cubeName, err)
+	}
+	var resp struct {
+		Position map[string]float64 `json:"position"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil || resp.Position == nil {
+		return nil, fmt.Errorf("cube %s position: unexpected reply %q", cubeName, raw)
+	}
+	if ok, bad := sanitizeVec(d.Config.Coordinates, resp.Position); !ok {
+		return nil, fmt.Errorf("cube %s position: %v not finite", cubeName, bad)
+	}
+	return []float64{resp.Position["x"], resp.Position["y"], resp.Position["z"]}, nil
+}
+
+// ClosestPlanetToCube looks up a cube's position and returns the nearest planet
+// and its distance.
+func (d *Discover) ClosestPlanetToCube(cubeName string) (string, float64, error) {
+	pos, err := d.CubePosition(cubeName)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(d.Planets) == 0 {
+		return "", 0, fmt.Errorf("no planets discovered")
+	}
+	name, dist := d.FindClosestPlanet(pos)
+	return name, dist, nil
+}
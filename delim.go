@@ -0,0 +1,175 @@
+package discover
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Delimiter auto-detection ---
+//
+// With Config.AutoDelimiter, dialPod sends the auth message terminated by
+// each candidate delimiter in turn, on a fresh connection each time. The
+// candidate the pod reacts to gets a JSON reply, and whatever bytes follow
+// that JSON value are the pod's own delimiter, which is then used for the rest
+// of the connection. A plain-text reply containing "auth_success" (what pods
+// were always checked for) also counts, with the candidate as the delimiter. A wrong candidate costs one short timeout instead of a
+// hang for the full TimeoutSec. Detected delimiters are remembered per pod
+// and tried first next time.
+
+// DefaultDelimiterCandidates are tried after Config.Delimiter.
+var DefaultDelimiterCandidates = []string{"<???DONE???---", "\n", "\r\n", "\x00"}
+
+// delimiterQuiet is how long detection waits for more delimiter bytes.
+const delimiterQuiet = 50 * time.Millisecond
+
+var (
+	errDelimiterDetect = errors.New("Delimiter detection failed")
+	detectedDelims     sync.Map // podMemoKey -> string, keyed by the candidate settings
+)
+
+// DetectedDelimiter returns the delimiter auto-detection found for pod with
+// cfg's Delimiter and DelimiterCandidates, if any.
+func DetectedDelimiter(cfg Config, pod PodRef) (string, bool) {
+	v, ok := detectedDelims.Load(delimKey(cfg, pod))
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+func delimKey(cfg Config, pod PodRef) podMemoKey {
+	cands := cfg.DelimiterCandidates
+	if cands == nil {
+		cands = DefaultDelimiterCandidates
+	}
+	return memoKey(pod, append([]string{cfg.Delimiter}, cands...)...)
+}
+
+func delimiterCandidates(cfg Config, pod PodRef) []string {
+	var out []string
+	add := func(d string) {
+		if d == "" {
+			return
+		}
+		for _, o := range out {
+			if o == d {
+				return
+			}
+		}
+		out = append(out, d)
+	}
+	if d, ok := DetectedDelimiter(cfg, pod); ok {
+		add(d)
+	}
+	add(cfg.Delimiter)
+	cands := cfg.DelimiterCandidates
+	if cands == nil {
+		cands = DefaultDelimiterCandidates
+	}
+	for _, d := range cands {
+		add(d)
+	}
+	return out
+}
+
+// detectAndAuth dials pod once per candidate until one yields a reply, and
+// returns the authenticated connection along with the auth reply.
+func detectAndAuth(cfg Config, pod PodRef) (msgTransport, string, error) {
+	wait := cfg.PreProbeTimeout
+	if wait <= 0 {
+		wait = 2 * time.Second
+	}
+	for _, cand := range delimiterCandidates(cfg, pod) {
+		t, err := dialTransport(cfg, pod)
+		if err != nil {
+			return nil, "", err
+		}
+		pc, ok := t.(*podConn)
+		if !ok || pc.framing != FramingDelimiter {
+			t.Close()
+			return nil, "", errDelimiterDetect
+		}
+		if err := sendMsg(pc.conn, cfg.AuthPass, cand); err != nil {
+			pc.Close()
+			return nil, "", errAuthFailed
+		}
+		reply, delim, ok := pc.readUnknownDelimited(wait, cand)
+		if !ok {
+			pc.Close()
+			continue
+		}
+		if delim == "" {
+			delim = cand // the reply ended exactly at the JSON value; assume symmetry
+		}
+		pc.delim = delim
+		detectedDelims.Store(delimKey(cfg, pod), delim)
+		return pc, reply, nil
+	}
+	return nil, "", errDelimiterDetect
+}
+
+// readUnknownDelimited reads a JSON reply whose terminator isn't known yet and
+// returns the reply plus the bytes that followed it. A non-JSON reply is
+// accepted if it contains "auth_success"; its delimiter is cand.
+func (c *podConn) readUnknownDelimited(wait time.Duration, cand string) (string, string, bool) {
+	var buf bytes.Buffer
+	deadline := time.Now().Add(wait)
+	chunk := make([]byte, 4096)
+	end := -1
+	for {
+		if end >= 0 {
+			// JSON complete; collect the trailing delimiter until the line is quiet.
+			c.conn.SetReadDeadline(time.Now().Add(delimiterQuiet))
+		} else {
+			c.conn.SetReadDeadline(deadline)
+		}
+		n, err := c.reader.Read(chunk)
+		buf.Write(chunk[:n])
+		if end < 0 {
+			end = jsonValueEnd(buf.Bytes())
+		}
+		if end < 0 && bytes.HasSuffix(buf.Bytes(), []byte(cand)) && plainAuthReply(buf.Bytes()) {
+			break
+		}
+		if err != nil {
+			break
+		}
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	if end < 0 {
+		if !plainAuthReply(buf.Bytes()) {
+			return "", "", false
+		}
+		reply := strings.TrimSuffix(buf.String(), cand)
+		return strings.TrimSpace(reply), cand, true
+	}
+	data := buf.Bytes()
+	return strings.TrimSpace(string(data[:end])), string(data[end:]), true
+}
+
+// plainAuthReply is the baseline auth check, for pods that don't answer JSON.
+func plainAuthReply(b []byte) bool {
+	return bytes.Contains(b, []byte("auth_success"))
+}
+
+// jsonValueEnd returns the offset just past the first complete JSON object in
+// b (after leading whitespace), or -1 if it isn't complete yet.
+func jsonValueEnd(b []byte) int {
+	start := 0
+	for start < len(b) && (b[start] == ' ' || b[start] == '\t' || b[start] == '\r' || b[start] == '\n') {
+		start++
+	}
+	if start == len(b) || b[start] != '{' {
+		return -1
+	}
+	dec := json.NewDecoder(bytes.NewReader(b[start:]))
+	var v json.RawMessage
+	if err := dec.Decode(&v); err != nil {
+		return -1
+	}
+	return start + int(dec.InputOffset())
+}
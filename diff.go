@@ -0,0 +1,130 @@
+package discover
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// --------- WORLD DIFF ---------
+
+// PlanetMove is a planet whose position changed by more than the tolerance.
+type PlanetMove struct {
+	Name     string
+	From, To [3]float64
+	Distance float64
+	PodRef   // owner in the newer state
+}
+
+func (m PlanetMove) String() string {
+	return fmt.Sprintf("%s moved %.3f: (%.3f, %.3f, %.3f) -> (%.3f, %.3f, %.3f)",
+		m.Name, m.Distance, m.From[0], m.From[1], m.From[2], m.To[0], m.To[1], m.To[2])
+}
+
+// PodChange is a pod whose scan status changed. Status is "ok", the scan
+// error, or "" when the pod was not scanned in that state.
+type PodChange struct {
+	PodRef
+	Before, After string
+}
+
+func (c PodChange) String() string {
+	show := func(s string) string {
+		if s == "" {
+			return "not scanned"
+		}
+		return s
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.PodRef, show(c.Before), show(c.After))
+}
+
+// WorldDiff is what changed between two states. Slices are sorted by planet
+// name or pod.
+type WorldDiff struct {
+	From, To time.Time
+	Added    []PlanetRecord
+	Removed  []PlanetRecord
+	Moved    []PlanetMove
+	Pods     []PodChange
+}
+
+// Empty reports whether nothing changed.
+func (w WorldDiff) Empty() bool {
+	return len(w.Added) == 0 && len(w.Removed) == 0 && len(w.Moved) == 0 && len(w.Pods) == 0
+}
+
+// DiffSnapshots compares two snapshots. Planets are matched by name (the
+// Planets map key); a planet counts as moved when it is more than tolerance
+// away from its old position.
+func DiffSnapshots(old, cur Snapshot, tolerance float64) WorldDiff {
+	w := WorldDiff{From: old.TakenAt, To: cur.TakenAt}
+	for name, p := range cur.Planets {
+		prev, ok := old.Planets[name]
+		if !ok {
+			w.Added = append(w.Added, p)
+			continue
+		}
+		if dist := distance3(prev.Coordinates, p.Coordinates); dist > tolerance {
+			w.Moved = append(w.Moved, PlanetMove{Name: name, From: prev.Coordinates, To: p.Coordinates, Distance: dist, PodRef: p.PodRef})
+		}
+	}
+	for name, p := range old.Planets {
+		if _, ok := cur.Planets[name]; !ok {
+			w.Removed = append(w.Removed, p)
+		}
+	}
+
+	before, after := podStatuses(old.Results), podStatuses(cur.Results)
+	for pod, s := range after {
+		if before[pod] != s {
+			w.Pods = append(w.Pods, PodChange{PodRef: pod, Before: before[pod], After: s})
+		}
+	}
+	for pod, s := range before {
+		if _, ok := after[pod]; !ok {
+			w.Pods = append(w.Pods, PodChange{PodRef: pod, Before: s})
+		}
+	}
+
+	sort.Slice(w.Added, func(i, j int) bool { return w.Added[i].Name < w.Added[j].Name })
+	sort.Slice(w.Removed, func(i, j int) bool { return w.Removed[i].Name < w.Removed[j].Name })
+	sort.Slice(w.Moved, func(i, j int) bool { return w.Moved[i].Name < w.Moved[j].Name })
+	sort.Slice(w.Pods, func(i, j int) bool {
+		if w.Pods[i].Host != w.Pods[j].Host {
+			return w.Pods[i].Host < w.Pods[j].Host
+		}
+		return w.Pods[i].Port < w.Pods[j].Port
+	})
+	return w
+}
+
+// podStatuses maps each pod to its latest status in results.
+func podStatuses(results []PodResult) map[PodRef]string {
+	out := make(map[PodRef]string, len(results))
+	for _, r := range results {
+		if r.Success {
+			out[r.PodRef] = "ok"
+		} else {
+			out[r.PodRef] = r.Error
+		}
+	}
+	return out
+}
+
+// Diff compares the current state against an earlier snapshot, e.g. one
+// returned by HistorySnapshot or read back from SaveSnapshot output.
+func (d *Discover) Diff(old Snapshot, tolerance float64) WorldDiff {
+	d.mu.Lock()
+	cur := d.snapshotLocked()
+	d.mu.Unlock()
+	return DiffSnapshots(old, cur, tolerance)
+}
+
+// DiffHistory compares the current state against the stored snapshot taken at.
+func (d *Discover) DiffHistory(at time.Time, tolerance float64) (WorldDiff, error) {
+	old, err := d.HistorySnapshot(at)
+	if err != nil {
+		return WorldDiff{}, err
+	}
+	return d.Diff(old, tolerance), nil
+}
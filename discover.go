@@ -1,8 +1,11 @@
 package discover
 
 import (
-	"fmt"
+	"crypto/tls"
+	"errors"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Only use PodResult and PlanetRecord from pod.go!
@@ -10,80 +13,286 @@ import (
 type Discover struct {
 	Config  Config
 	Results []PodResult
-	Planets map[string]PlanetRecord
-	Cubes   map[string]string // cubeName -> host
-	mu      sync.Mutex
+	Planets map[string]PlanetRecord // name -> planet, across all universes
+	// UniversePlanets keys planets by universe, then name, so equally named
+	// planets in different universes stay distinct.
+	UniversePlanets map[string]map[string]PlanetRecord
+	Cubes           map[string]CubeRecord // cubeName -> cube and its pod
+	mu              sync.Mutex
+	probed          []PodRef    // pods found by the last UDP probe
+	pool            *ClientPool // lazily created by Client
+	derived         derivedCache
+	quarantine      quarantine              // pods skipped after repeated auth failures
+	warnings        chan Warning            // created by Warnings
+	constellations  map[string][]string     // name -> sorted planet names
+	conflicts       []PlanetConflict        // name collisions seen by the last ScanAll
+	collided        map[string]bool         // scope+"\x00"+name of names held apart by CollideNamespace or CollideError
+	metrics         scanMetrics             // see Collector
+	hooks           hooks                   // registered by OnPlanetDiscovered and friends
+	labels          map[string]PlanetLabels // planet name -> tags and annotations
+	sessions        []Session
+	sessionSeq      int
+	index           spatialIndex       // KD-tree over Planets, see FindClosestPlanet
+	radii           map[string]float64 // planet name -> radius set by SetPlanetRadius
+	units           UnitRegistry       // cubes spawned through Discover, see Units
+	mem             *MemoryStore       // fallback when Config.Store is nil, see store
+	memOnce         sync.Once
 }
 
 type Config struct {
-	Hosts      []string
-	StartPort  int
-	PortStep   int
-	NumPods    int
-	AuthPass   string
-	Delimiter  string // Now part of the config
-	TimeoutSec int
+	Hosts               []string
+	StartPort           int
+	PortStep            int
+	NumPods             int
+	AuthPass            string
+	Delimiter           string // Now part of the config
+	TimeoutSec          int
+	Framing             Framing           // FramingDelimiter (default) or FramingLengthPrefix
+	Probe               *ProbeConfig      // optional UDP probe; answering pods are scanned too
+	Steps               []ScanStep        // requests sent per pod, in order; nil = DefaultScanSteps
+	Pool                PoolConfig        // limits for clients returned by Discover.Client
+	Compression         string            // "" (off) or CompressionGzip; used only if the pod agrees
+	Multiplex           bool              // tag PodClient commands with request_id and allow concurrent callers
+	PreProbeTimeout     time.Duration     // if set, bounds dial + auth reply and rejects non-pod services early
+	Dialer              Dialer            // optional custom dialer (SOCKS5, SSH tunnel, ...); nil uses net.Dialer
+	AutoDelimiter       bool              // detect the pod delimiter during auth instead of trusting Delimiter
+	DelimiterCandidates []string          // delimiters tried by AutoDelimiter; nil = Delimiter then DefaultDelimiterCandidates
+	Universes           []string          // if set, get_planets is requested once per named universe
+	Protocol            Protocol          // ProtocolJSON (default) or ProtocolProtobuf, if the pod agrees
+	Store               StateStore        // persistence backend for saved state; nil = in-memory
+	Pipeline            bool              // send all scan requests before reading replies (one round trip per pod)
+	DialTimeout         time.Duration     // connect (+TLS) timeout; 0 = TimeoutSec
+	ReadTimeout         time.Duration     // per-reply read timeout; 0 = TimeoutSec
+	WriteTimeout        time.Duration     // per-message write timeout; 0 = TimeoutSec
+	QuarantineAfter     int               // skip pods after this many consecutive "Bad password" scans; 0 = never
+	AuthPasses          []string          // passwords tried in order on "Bad password"; overrides AuthPass when set
+	TLS                 *tls.Config       // if set, TCP pods are dialed over TLS (wss:// always is); cloned per dial
+	ServerNames         map[string]string // per-target TLS server name, keyed by "host:port", host or URL
+	Coordinates         CoordinatePolicy  // non-finite coordinates: CoordReject (default), CoordClamp or CoordKeep
+	ReadRate            int               // per-connection read limit in bytes/s; 0 = unlimited
+	WriteRate           int               // per-connection write limit in bytes/s; 0 = unlimited
+	Collisions          CollisionStrategy // same planet name from several pods: CollideFirstWins (default), CollideLastWins, CollideMerge, CollideNamespace, CollideError, CollideNewest
+	History             ScanStorage       // if set, every ScanAll is recorded (e.g. NewSQLStorage)
+	SnapshotHistory     bool              // if true, every ScanAll saves a snapshot to Store under history/ (see HistoryRuns)
+	HistoryRetention    Retention         // bounds the snapshot history by count and/or age
+	HistoryEncoding     SnapshotEncoding  // encoding of history snapshots; SnapshotGob is faster for large worlds
+	PlanetRadius        RadiusFunc        // surface radius per planet, e.g. derived from its Seed; nil falls back to DefaultPlanetRadius
+	DefaultPlanetRadius float64           // radius when neither SetPlanetRadius nor PlanetRadius gives one
+	PlanetMass          MassFunc          // gravitational weight per planet for GravityAt and DominantPlanet; nil = 1 for every planet
+	SystemRadius        float64           // planets this close to each other share a system (see Systems); 0 = every planet alone
+	SystemMinPlanets    int               // DBSCAN core size for Systems; planets in no group this dense are left out; <= 1 keeps all
+	MoveClearance       float64           // MoveCube refuses destinations closer than this to any planet (IsSpawnPointFree); 0 = DefaultMoveClearance, < 0 skips the check
+	WorldBounds         map[PodRef]Bounds // per-pod box MoveCube destinations must lie in; pods without an entry are unbounded
+}
+
+// dialTimeout bounds connection setup; PreProbeTimeout shortens it.
+func (cfg Config) dialTimeout() time.Duration {
+	if cfg.PreProbeTimeout > 0 {
+		return cfg.PreProbeTimeout
+	}
+	return cfg.orTimeoutSec(cfg.DialTimeout)
+}
+
+// readTimeout bounds the wait for each reply.
+func (cfg Config) readTimeout() time.Duration { return cfg.orTimeoutSec(cfg.ReadTimeout) }
+
+// writeTimeout bounds each message write.
+func (cfg Config) writeTimeout() time.Duration { return cfg.orTimeoutSec(cfg.WriteTimeout) }
+
+func (cfg Config) orTimeoutSec(d time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return time.Duration(cfg.TimeoutSec) * time.Second
 }
 
 func NewDiscover(cfg Config) *Discover {
 	return &Discover{
-		Config:  cfg,
-		Planets: make(map[string]PlanetRecord),
-		Cubes:   make(map[string]string),
+		Config:          cfg,
+		Planets:         make(map[string]PlanetRecord),
+		UniversePlanets: make(map[string]map[string]PlanetRecord),
+		Cubes:           make(map[string]CubeRecord),
+	}
+}
+
+// podAddrs expands Hosts x NumPods into the list of configured pods. A host
+// given as a ws://, wss:// or unix:// URL is a single pod and is not
+// port-expanded.
+func (d *Discover) podAddrs() []PodRef {
+	addrs := make([]PodRef, 0, d.Config.NumPods*len(d.Config.Hosts))
+	for _, host := range d.Config.Hosts {
+		if isURLTarget(host) {
+			addrs = append(addrs, PodRef{Host: host, Port: urlTargetPort(host)})
+			continue
+		}
+		for i := 0; i < d.Config.NumPods; i++ {
+			addrs = append(addrs, PodRef{Host: host, Port: d.Config.StartPort + i*d.Config.PortStep})
+		}
+	}
+	d.mu.Lock()
+	probed := d.probed
+	d.mu.Unlock()
+	for _, p := range probed {
+		if !containsAddr(addrs, p) {
+			addrs = append(addrs, p)
+		}
 	}
+	return addrs
+}
+
+func containsAddr(addrs []PodRef, a PodRef) bool {
+	for _, b := range addrs {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeLAN runs the configured UDP probe and remembers the pods that answered,
+// so the next ScanAll includes them. ScanAll calls it automatically when
+// Config.Probe is set.
+func (d *Discover) ProbeLAN() ([]PodRef, error) {
+	if d.Config.Probe == nil {
+		return nil, errors.New("no probe configured")
+	}
+	replies, err := ProbeUDP(*d.Config.Probe)
+	d.mu.Lock()
+	d.probed = replies
+	d.mu.Unlock()
+	return replies, err
 }
 
 func (d *Discover) ScanAll() {
+	if d.Config.Probe != nil {
+		d.ProbeLAN()
+	}
+	started := time.Now()
+	d.mu.Lock()
+	session := d.beginSessionLocked(started)
+	d.mu.Unlock()
 	var wg sync.WaitGroup
-	resultsChan := make(chan PodResult, d.Config.NumPods*len(d.Config.Hosts))
+	addrs := d.podAddrs()
+	results := make([]PodResult, len(addrs))
 
-	for _, host := range d.Config.Hosts {
-		for i := 0; i < d.Config.NumPods; i++ {
-			port := d.Config.StartPort + i*d.Config.PortStep
-			wg.Add(1)
-			go func(host string, port int) {
-				defer wg.Done()
-				result := ScanPod(host, port, d.Config.AuthPass, d.Config.Delimiter, d.Config.TimeoutSec)
-				resultsChan <- result
-			}(host, port)
+	for i, addr := range addrs {
+		d.mu.Lock()
+		skip := d.isQuarantined(addr)
+		d.mu.Unlock()
+		if skip {
+			results[i] = PodResult{PodRef: addr, Error: errQuarantined.Error(), Session: session, ScannedAt: started}
+			continue
 		}
+		wg.Add(1)
+		go func(pod PodRef) {
+			defer wg.Done()
+			results[i] = ScanPodConfig(d.Config, pod)
+			results[i].Session, results[i].ScannedAt = session, time.Now()
+		}(addr)
 	}
 
 	wg.Wait()
-	close(resultsChan)
 
-	for result := range resultsChan {
-		d.mu.Lock()
+	// Merge in configured pod order so name collisions resolve the same way
+	// on every scan.
+	d.mu.Lock()
+	before := d.knownLocked()
+	d.conflicts, d.collided = nil, nil
+	for _, result := range results {
 		d.Results = append(d.Results, result)
-		if result.Success {
-			for _, planet := range result.Planets {
-				d.Planets[planet.Name] = planet
-			}
-			for _, cube := range result.Cubes {
-				d.Cubes[cube] = result.Host
-			}
+		d.recordAuthLocked(result)
+		d.emitWarningsLocked(result)
+		d.mergeLocked(result)
+	}
+	d.endSessionLocked(session, started, results, false)
+	d.mu.Unlock()
+	d.invalidateChanged()
+	d.metrics.observeScan(time.Since(started), results)
+	d.recordRun(started, results)
+	d.recordHistory()
+	d.fireHooks(before, results)
+}
+
+// mergeLocked adds a successful result's planets and cubes. d.mu must be held.
+func (d *Discover) mergeLocked(result PodResult) {
+	if !result.Success {
+		return
+	}
+	for _, planet := range result.Planets {
+		d.placePlanetLocked(d.Planets, flatScope, planet)
+		byName, ok := d.UniversePlanets[planet.Universe]
+		if !ok {
+			byName = make(map[string]PlanetRecord)
+			d.UniversePlanets[planet.Universe] = byName
 		}
-		d.mu.Unlock()
+		d.placePlanetLocked(byName, planet.Universe, planet)
+	}
+	for _, cube := range result.Cubes {
+		d.Cubes[cube] = CubeRecord{Name: cube, PodRef: result.PodRef, State: result.CubeState[cube]}
 	}
 }
 
-func (d *Discover) PrintSummary() {
-	totalCubes, totalPlanets, successCount := 0, 0, 0
-	fmt.Println("\n=== D.I.S.C.O.V.E.R.™ SUMMARY ===")
-	for _, res := range d.Results {
-		if res.Success {
-			successCount++
-			totalCubes += len(res.Cubes)
-			totalPlanets += len(res.Planets)
-			fmt.Printf("[%s:%d] ✅ Cubes=%d Planets=%d\n", res.Host, res.Port, len(res.Cubes), len(res.Planets))
-		} else {
-			fmt.Printf("[%s:%d] ❌ %s\n", res.Host, res.Port, res.Error)
+// RescanPods scans just the given pods and replaces what was known about
+// them: their previous results, planets and cubes are dropped first, so data
+// they no longer report disappears.
+func (d *Discover) RescanPods(pods ...PodRef) []PodResult {
+	started := time.Now()
+	d.mu.Lock()
+	session := d.beginSessionLocked(started)
+	d.mu.Unlock()
+	results := make([]PodResult, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = ScanPodConfig(d.Config, pod)
+			results[i].Session, results[i].ScannedAt = session, time.Now()
+		}()
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	before := d.knownLocked()
+	for _, result := range results {
+		pod := result.PodRef
+		kept := d.Results[:0]
+		for _, r := range d.Results {
+			if r.PodRef != pod {
+				kept = append(kept, r)
+			}
 		}
+		d.Results = append(kept, result)
+		d.recordAuthLocked(result)
+		d.emitWarningsLocked(result)
+		for name, p := range d.Planets {
+			if p.PodRef == pod {
+				delete(d.Planets, name)
+			}
+		}
+		for u, byName := range d.UniversePlanets {
+			for name, p := range byName {
+				if p.PodRef == pod {
+					delete(byName, name)
+				}
+			}
+			if len(byName) == 0 {
+				delete(d.UniversePlanets, u)
+			}
+		}
+		for cube, c := range d.Cubes {
+			if c.PodRef == pod {
+				delete(d.Cubes, cube)
+			}
+		}
+		d.mergeLocked(result)
 	}
-	fmt.Printf("\nSuccessful pods: %d / %d\n", successCount, d.Config.NumPods*len(d.Config.Hosts))
-	fmt.Printf("Total Cubes: %d\n", totalCubes)
-	fmt.Printf("Total Planets: %d\n", totalPlanets)
-	fmt.Printf("Unique Planets: %d\n", len(d.Planets))
+	d.endSessionLocked(session, started, results, true)
+	d.mu.Unlock()
+	d.invalidateChanged()
+	d.metrics.observePods(results)
+	d.fireHooks(before, results)
+	return results
 }
 
 // ExtractPlanetCenters returns a slice of [x, y, z] float64 slices for each planet discovered.
@@ -97,3 +306,71 @@ func (d *Discover) ExtractPlanetCenters() [][]float64 {
 	}
 	return centers
 }
+
+// PlanetList returns a copy of the discovered planets, sorted by name. Unlike
+// reading Planets directly it is safe while a scan is running.
+func (d *Discover) PlanetList() []PlanetRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]PlanetRecord, 0, len(d.Planets))
+	for _, p := range d.Planets {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LookupPlanet returns the planet stored under name.
+func (d *Discover) LookupPlanet(name string) (PlanetRecord, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.Planets[name]
+	return p, ok
+}
+
+// CubeNames lists the discovered cubes, sorted.
+func (d *Discover) CubeNames() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]string, 0, len(d.Cubes))
+	for name := range d.Cubes {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LookupCube returns a discovered cube and the pod hosting it.
+func (d *Discover) LookupCube(name string) (CubeRecord, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.Cubes[name]
+	return c, ok
+}
+
+// ResultList returns a copy of the scan results so far.
+func (d *Discover) ResultList() []PodResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]PodResult(nil), d.Results...)
+}
+
+// Universes lists the universes discovered so far, sorted.
+func (d *Discover) Universes() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]string, 0, len(d.UniversePlanets))
+	for u := range d.UniversePlanets {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// UniversePlanet looks up a planet by (universe, name).
+func (d *Discover) UniversePlanet(universe, name string) (PlanetRecord, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.UniversePlanets[universe][name]
+	return p, ok
+}
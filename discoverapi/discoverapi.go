@@ -0,0 +1,110 @@
+// Package discoverapi is an interface-based view of D.I.S.C.O.V.E.R.
+//
+// It exposes behaviour through interfaces (Scanner, SpawnPlanner, Store)
+// instead of the concrete Discover struct and its exported maps, so
+// downstream projects can mock it. It lives in the same module as the root
+// package and changes with it; there is no separate compatibility promise.
+// Data types are aliases of the root package's, so values pass freely
+// between the two APIs.
+package discoverapi
+
+import (
+	v1 "github.com/OpenFluke/discover"
+)
+
+// Shared data types.
+type (
+	Config         = v1.Config
+	PodRef         = v1.PodRef
+	PodResult      = v1.PodResult
+	PlanetRecord   = v1.PlanetRecord
+	CubeRecord     = v1.CubeRecord
+	SpawnPlan      = v1.SpawnPlan
+	SpawnPoint     = v1.SpawnPoint
+	Warning        = v1.Warning
+	PlanetConflict = v1.PlanetConflict
+)
+
+// Store persists state (snapshots, constellations, history, ...).
+// NewFileStore and NewMemoryStore provide implementations.
+type Store = v1.StateStore
+
+var (
+	NewFileStore   = v1.NewFileStore
+	NewMemoryStore = v1.NewMemoryStore
+	ErrNotFound    = v1.ErrNotFound
+)
+
+// Scanner discovers pods and answers questions about what was found. All
+// methods return copies and are safe to call while a scan is running.
+type Scanner interface {
+	// Scan scans every configured pod. With Config.Collisions set to
+	// CollideError it returns the planet name conflicts it found.
+	Scan() error
+	Results() []PodResult
+	Planets() []PlanetRecord // sorted by name
+	Planet(name string) (PlanetRecord, bool)
+	Universes() []string
+	CubeNames() []string
+	Cube(name string) (CubeRecord, bool) // the cube and the pod hosting it
+}
+
+// SpawnPlanner proposes spawn positions using the discovered planets.
+type SpawnPlanner interface {
+	PlanSpawns(planet string, n int, radius, minDist float64) (*SpawnPlan, error)
+	SpawnPositions(planet string, n int, radius float64) ([][]float64, error)
+	IsSpawnPointFree(point []float64, minDist float64) bool
+	ClosestPlanet(point []float64) (string, float64)
+}
+
+// Service is the full API: scanning plus spawn planning.
+type Service interface {
+	Scanner
+	SpawnPlanner
+}
+
+// New returns a Service for cfg.
+func New(cfg Config) Service {
+	return &service{d: v1.NewDiscover(cfg)}
+}
+
+// Wrap exposes an existing root-package Discover as a Service, for code that
+// moves over piece by piece.
+func Wrap(d *v1.Discover) Service {
+	return &service{d: d}
+}
+
+type service struct {
+	d *v1.Discover
+}
+
+func (s *service) Scan() error {
+	s.d.ScanAll()
+	if s.d.Config.Collisions == v1.CollideError {
+		return s.d.CheckConflicts()
+	}
+	return nil
+}
+
+func (s *service) Results() []PodResult                    { return s.d.ResultList() }
+func (s *service) Planets() []PlanetRecord                 { return s.d.PlanetList() }
+func (s *service) Planet(name string) (PlanetRecord, bool) { return s.d.LookupPlanet(name) }
+func (s *service) Universes() []string                     { return s.d.Universes() }
+func (s *service) CubeNames() []string                     { return s.d.CubeNames() }
+func (s *service) Cube(name string) (CubeRecord, bool)     { return s.d.LookupCube(name) }
+
+func (s *service) PlanSpawns(planet string, n int, radius, minDist float64) (*SpawnPlan, error) {
+	return s.d.PlanSpawns(planet, n, radius, minDist)
+}
+
+func (s *service) SpawnPositions(planet string, n int, radius float64) ([][]float64, error) {
+	return s.d.GenerateSpawnPositions(planet, n, radius)
+}
+
+func (s *service) IsSpawnPointFree(point []float64, minDist float64) bool {
+	return s.d.IsSpawnPointFree(point, minDist)
+}
+
+func (s *service) ClosestPlanet(point []float64) (string, float64) {
+	return s.d.FindClosestPlanet(point)
+}
@@ -26,8 +26,8 @@ func main() {
 	}
 
 	fmt.Println("\n-- Discovered Cubes --")
-	for cube, host := range disco.Cubes {
-		fmt.Printf("%s at %s\n", cube, host)
+	for cube, rec := range disco.Cubes {
+		fmt.Printf("%s at %s\n", cube, rec.PodRef)
 	}
 
 	// --- Use the new extras.go features ---
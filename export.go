@@ -0,0 +1,164 @@
+package discover
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --------- EXPORT FORMATTING ---------
+
+// NumberFormat controls how coordinates are rendered in table exports.
+type NumberFormat struct {
+	Precision          int     // digits after the decimal point; 0 = 3, NoDecimals for none, -1 = shortest exact form
+	SciThreshold       float64 // values with |v| >= SciThreshold use scientific notation; 0 disables
+	DecimalSeparator   string  // defaults to "."
+	ThousandsSeparator string  // digit grouping for the integer part; empty disables
+}
+
+// DefaultNumberFormat matches the historical "%.3f" output; the zero
+// NumberFormat renders the same.
+var DefaultNumberFormat = NumberFormat{Precision: 3}
+
+// NoDecimals is the Precision that rounds to whole numbers.
+const NoDecimals = -2
+
+// prec maps Precision to strconv's: 0 is the default, NoDecimals is 0.
+func (f NumberFormat) prec() int {
+	switch f.Precision {
+	case 0:
+		return DefaultNumberFormat.Precision
+	case NoDecimals:
+		return 0
+	}
+	return f.Precision
+}
+
+// Format renders v according to the format.
+func (f NumberFormat) Format(v float64) string {
+	prec := f.prec()
+	dec := f.DecimalSeparator
+	if dec == "" {
+		dec = "."
+	}
+	if f.SciThreshold > 0 && math.Abs(v) >= f.SciThreshold && !math.IsInf(v, 0) {
+		return strings.Replace(strconv.FormatFloat(v, 'e', prec, 64), ".", dec, 1)
+	}
+
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	if f.ThousandsSeparator != "" {
+		intPart = groupDigits(intPart, f.ThousandsSeparator)
+	}
+	if !hasFrac {
+		return intPart
+	}
+	return intPart + dec + frac
+}
+
+// groupDigits inserts sep every three digits, leaving any sign in place.
+func groupDigits(s, sep string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+	var b strings.Builder
+	lead := len(s) % 3
+	if lead > 0 {
+		b.WriteString(s[:lead])
+	}
+	for i := lead; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return sign + b.String()
+}
+
+// --------- COORDINATE QUANTIZATION ---------
+
+// Quantize rounds v to the nearest multiple of step (e.g. 0.01). The result is
+// also trimmed to as many decimals as step has so it encodes to short JSON
+// (12.34, not 12.340000000000002). A step <= 0 returns v unchanged.
+func Quantize(v, step float64) float64 {
+	if step <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	q := math.Round(v/step) * step
+	digits := 0
+	if _, frac, ok := strings.Cut(strconv.FormatFloat(step, 'f', -1, 64), "."); ok {
+		digits = len(frac)
+	}
+	trimmed, err := strconv.ParseFloat(strconv.FormatFloat(q, 'f', digits, 64), 64)
+	if err != nil {
+		return q
+	}
+	return trimmed
+}
+
+// QuantizeCoordinates applies Quantize to each axis.
+func QuantizeCoordinates(c [3]float64, step float64) [3]float64 {
+	return [3]float64{Quantize(c[0], step), Quantize(c[1], step), Quantize(c[2], step)}
+}
+
+// ExportOptions tunes JSON exports.
+type ExportOptions struct {
+	Quantum float64 // round coordinates to multiples of this (e.g. 0.01); 0 keeps full precision
+}
+
+// quantizeAll returns quantized copies, leaving the stored slices untouched.
+func quantizeAll(locs [][3]float64, q float64) [][3]float64 {
+	if q <= 0 || len(locs) == 0 {
+		return locs
+	}
+	out := make([][3]float64, len(locs))
+	for i, l := range locs {
+		out[i] = QuantizeCoordinates(l, q)
+	}
+	return out
+}
+
+// ExportPlanetsJSON writes the discovered planets as a JSON array sorted by name.
+func (d *Discover) ExportPlanetsJSON(w io.Writer, opts ExportOptions) error {
+	planets := make([]PlanetRecord, 0, len(d.Planets))
+	for _, p := range d.Planets {
+		p.Coordinates = QuantizeCoordinates(p.Coordinates, opts.Quantum)
+		p.ResourceLocations = quantizeAll(p.ResourceLocations, opts.Quantum)
+		p.TreeLocations = quantizeAll(p.TreeLocations, opts.Quantum)
+		planets = append(planets, p)
+	}
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+	return json.NewEncoder(w).Encode(planets)
+}
+
+// ExportPlanetsCSV writes GetPlanetInfoTable as CSV, header row first.
+func (d *Discover) ExportPlanetsCSV(w io.Writer) error {
+	return writeCSV(w, d.GetPlanetInfoTable())
+}
+
+// ExportCubesCSV writes the discovered cubes (name, host, port) as CSV,
+// sorted by name.
+func (d *Discover) ExportCubesCSV(w io.Writer) error {
+	table := [][]string{{"Name", "Host", "Port"}}
+	for _, name := range d.CubeNames() {
+		c, _ := d.LookupCube(name)
+		table = append(table, []string{c.Name, c.Host, strconv.Itoa(c.Port)})
+	}
+	return writeCSV(w, table)
+}
+
+func writeCSV(w io.Writer, table [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(table); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -11,16 +11,23 @@ import (
 
 // 1. Generate evenly distributed points around a planet center using a Fibonacci sphere algorithm.
 func FibonacciSphere(n int, radius float64, center []float64) [][]float64 {
-	points := make([][]float64, n)
+	vs := FibonacciSphereVec(n, radius, Vec3Of(center))
+	points := make([][]float64, len(vs))
+	for i, v := range vs {
+		points[i] = v.Slice()
+	}
+	return points
+}
+
+// FibonacciSphereVec is FibonacciSphere on Vec3.
+func FibonacciSphereVec(n int, radius float64, center Vec3) []Vec3 {
+	defer perfTrack("FibonacciSphere")()
+	points := make([]Vec3, n)
 	if n == 0 {
 		return points
 	}
 	if n == 1 {
-		points[0] = []float64{
-			center[0] + radius,
-			center[1],
-			center[2],
-		}
+		points[0] = center.Add(V3(radius, 0, 0))
 		return points
 	}
 	phi := math.Pi * (3 - math.Sqrt(5)) // Golden angle in radians
@@ -30,55 +37,63 @@ func FibonacciSphere(n int, radius float64, center []float64) [][]float64 {
 		theta := phi * float64(i)
 		x := math.Cos(theta) * r
 		z := math.Sin(theta) * r
-		points[i] = []float64{
-			center[0] + x*radius,
-			center[1] + y*radius,
-			center[2] + z*radius,
-		}
+		points[i] = center.Add(V3(x, y, z).Scale(radius))
 	}
 	return points
 }
 
-// 2. For a given planet, generate spawn positions on a sphere around it.
+// 2. For a given planet, generate spawn positions on a sphere around it (cached until a rescan changes the planet).
 func (d *Discover) GenerateSpawnPositions(planetName string, n int, radius float64) ([][]float64, error) {
-	planet, ok := d.Planets[planetName]
-	if !ok {
-		return nil, fmt.Errorf("planet %s not found", planetName)
+	if n < 0 {
+		return nil, fmt.Errorf("negative spawn count %d", n)
+	}
+	v, err := d.Derived(planetName, fmt.Sprintf("spawn/fib/%d/%g", n, radius), func(planet PlanetRecord) (any, error) {
+		return FibonacciSphere(n, radius, []float64{
+			planet.Coordinates[0],
+			planet.Coordinates[1],
+			planet.Coordinates[2],
+		}), nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return FibonacciSphere(n, radius, []float64{
-		planet.Coordinates[0],
-		planet.Coordinates[1],
-		planet.Coordinates[2],
-	}), nil
+	return copyPoints(v.([][]float64)), nil
 }
 
 // 3. Calculate angle in degrees for an object at 'position' to face outward from a planet at 'center'
 func CalculateRotationOutward(center, position []float64) float64 {
-	dx := position[0] - center[0]
-	dz := position[2] - center[2]
-	angle := math.Atan2(dz, dx) * (180.0 / math.Pi)
-	return angle
+	return CalculateRotationOutwardVec(Vec3Of(center), Vec3Of(position))
+}
+
+// CalculateRotationOutwardVec is CalculateRotationOutward on Vec3.
+func CalculateRotationOutwardVec(center, position Vec3) float64 {
+	v := position.Sub(center)
+	return math.Atan2(v[2], v[0]) * (180.0 / math.Pi)
 }
 
 // 4. Find the closest planet to a given point (returns planet name and distance)
 func (d *Discover) FindClosestPlanet(point []float64) (string, float64) {
-	minDist := math.MaxFloat64
-	var closest string
-	for name, planet := range d.Planets {
-		dx := planet.Coordinates[0] - point[0]
-		dy := planet.Coordinates[1] - point[1]
-		dz := planet.Coordinates[2] - point[2]
-		dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
-		if dist < minDist {
-			minDist = dist
-			closest = name
-		}
+	return d.FindClosestPlanetVec(Vec3Of(point))
+}
+
+// FindClosestPlanetVec is FindClosestPlanet on Vec3.
+func (d *Discover) FindClosestPlanetVec(point Vec3) (string, float64) {
+	defer perfTrack("FindClosestPlanet")()
+	t := d.planetIndex()
+	i, d2 := t.nearest(point)
+	if i < 0 {
+		return "", math.MaxFloat64
 	}
-	return closest, minDist
+	return t.pts[i].name, math.Sqrt(d2)
 }
 
 // 5. Export planet table (name, x, y, z, host, port)
 func (d *Discover) GetPlanetInfoTable() [][]string {
+	return d.GetPlanetInfoTableFormat(DefaultNumberFormat)
+}
+
+// 5b. Same table with coordinates rendered using the given NumberFormat
+func (d *Discover) GetPlanetInfoTableFormat(f NumberFormat) [][]string {
 	table := [][]string{{"Name", "X", "Y", "Z", "Host", "Port"}}
 	// Optional: sorted order
 	names := make([]string, 0, len(d.Planets))
@@ -90,9 +105,9 @@ func (d *Discover) GetPlanetInfoTable() [][]string {
 		p := d.Planets[name]
 		table = append(table, []string{
 			p.Name,
-			fmt.Sprintf("%.3f", p.Coordinates[0]),
-			fmt.Sprintf("%.3f", p.Coordinates[1]),
-			fmt.Sprintf("%.3f", p.Coordinates[2]),
+			f.Format(p.Coordinates[0]),
+			f.Format(p.Coordinates[1]),
+			f.Format(p.Coordinates[2]),
 			p.Host,
 			fmt.Sprintf("%d", p.Port),
 		})
@@ -102,30 +117,35 @@ func (d *Discover) GetPlanetInfoTable() [][]string {
 
 // 6. Test if a proposed spawn point is at least 'minDist' away from all planets.
 func (d *Discover) IsSpawnPointFree(point []float64, minDist float64) bool {
-	for _, planet := range d.Planets {
-		dx := planet.Coordinates[0] - point[0]
-		dy := planet.Coordinates[1] - point[1]
-		dz := planet.Coordinates[2] - point[2]
-		dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
-		if dist < minDist {
-			return false
-		}
+	return d.IsSpawnPointFreeVec(Vec3Of(point), minDist)
+}
+
+// IsSpawnPointFreeVec is IsSpawnPointFree on Vec3.
+func (d *Discover) IsSpawnPointFreeVec(point Vec3, minDist float64) bool {
+	defer perfTrack("IsSpawnPointFree")()
+	if !(minDist > 0) { // nothing is closer than zero, a negative distance or NaN
+		return true
 	}
-	return true
+	free := true
+	d.planetIndex().within(point, minDist*minDist, false, func(int, float64) bool {
+		free = false
+		return false
+	})
+	return free
 }
 
 // 7. (Optional) Get outward normal vector for a point on a sphere centered at planet
 func OutwardNormal(center, point []float64) []float64 {
-	v := []float64{
-		point[0] - center[0],
-		point[1] - center[1],
-		point[2] - center[2],
-	}
-	mag := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
-	if mag == 0 {
-		return []float64{0, 1, 0}
+	return OutwardNormalVec(Vec3Of(center), Vec3Of(point)).Slice()
+}
+
+// OutwardNormalVec is OutwardNormal on Vec3.
+func OutwardNormalVec(center, point Vec3) Vec3 {
+	n := point.Sub(center).Normalize()
+	if n == (Vec3{}) {
+		return V3(0, 1, 0)
 	}
-	return []float64{v[0] / mag, v[1] / mag, v[2] / mag}
+	return n
 }
 
 func GenerateUnitID(role string, domain string, gen int, version int) string {
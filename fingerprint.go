@@ -0,0 +1,76 @@
+package discover
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strconv"
+)
+
+// --------- WORLD FINGERPRINT ---------
+
+// WorldFingerprint is a stable hash of the discovered world: every Planets
+// entry (name, universe, seed, biome, owning pod, and coordinates and
+// resource/tree locations rounded to multiples of tolerance) and every cube
+// with its pod, all in sorted order. Equal fingerprints mean nothing changed,
+// so callers can skip downstream work. Results, warnings and labels are not
+// included. A tolerance <= 0 hashes exact coordinates; note that a value
+// jittering across a rounding boundary still changes the hash.
+func (d *Discover) WorldFingerprint(tolerance float64) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return worldFingerprint(d.Planets, d.Cubes, tolerance)
+}
+
+// SnapshotFingerprint is WorldFingerprint for a stored snapshot, so a fresh
+// scan can be compared against history without loading it.
+func SnapshotFingerprint(s Snapshot, tolerance float64) string {
+	return worldFingerprint(s.Planets, s.Cubes, tolerance)
+}
+
+func worldFingerprint(planets map[string]PlanetRecord, cubes map[string]CubeRecord, tolerance float64) string {
+	h := sha256.New()
+	keys := make([]string, 0, len(planets))
+	for k := range planets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := planets[k]
+		fpString(h, "planet", k, p.Name, p.Universe, strconv.Itoa(p.Seed), strconv.Itoa(p.BiomeType), p.PodRef.String())
+		fpVec(h, p.Coordinates, tolerance)
+		for _, locs := range [][][3]float64{p.ResourceLocations, p.TreeLocations} {
+			fpString(h, strconv.Itoa(len(locs)))
+			for _, l := range locs {
+				fpVec(h, l, tolerance)
+			}
+		}
+	}
+	keys = keys[:0]
+	for k := range cubes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fpString(h, "cube", k, cubes[k].PodRef.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fpString writes NUL-terminated fields so adjacent values can't run together.
+func fpString(h hash.Hash, fields ...string) {
+	for _, f := range fields {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+}
+
+func fpVec(h hash.Hash, v [3]float64, tolerance float64) {
+	for _, c := range QuantizeCoordinates(v, tolerance) {
+		if c == 0 {
+			c = 0 // -0 and 0 hash alike
+		}
+		fpString(h, strconv.FormatFloat(c, 'g', -1, 64))
+	}
+}
@@ -0,0 +1,94 @@
+package discover
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// --------- GLTF EXPORT ---------
+
+// GLTFOptions tunes ExportGLTF. glTF is Y-up right-handed like Godot, so the
+// same AxisConvention applies.
+type GLTFOptions struct {
+	Axes  AxisConvention
+	Scale float64 // multiplies every position; 0 means 1
+}
+
+type gltfDoc struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []map[string]any `json:"meshes"`
+	Accessors   []map[string]any `json:"accessors"`
+	BufferViews []map[string]any `json:"bufferViews"`
+	Buffers     []map[string]any `json:"buffers"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string     `json:"name"`
+	Mesh        int        `json:"mesh"`
+	Translation [3]float64 `json:"translation"`
+	Extras      gltfExtras `json:"extras"`
+}
+
+type gltfExtras struct {
+	Universe  string `json:"universe,omitempty"`
+	Seed      int    `json:"seed"`
+	BiomeType int    `json:"biome_type"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+}
+
+// gltfPoint is one float32 vertex at the origin: a point mesh shared by every
+// planet node so viewers have something to draw.
+const gltfPoint = "data:application/octet-stream;base64,AAAAAAAAAAAAAAAA"
+
+// ExportGLTF writes the planets as a glTF 2.0 JSON file with one node per
+// planet (sorted by name): the node's translation is the planet's position
+// and its extras carry seed, biome, universe and the hosting pod.
+func (d *Discover) ExportGLTF(w io.Writer, opts GLTFOptions) error {
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	planets := d.PlanetList()
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+
+	doc := gltfDoc{
+		Asset:  gltfAsset{Version: "2.0", Generator: "github.com/OpenFluke/discover"},
+		Scenes: []gltfScene{{Name: "universe", Nodes: []int{}}},
+		Nodes:  []gltfNode{},
+		Meshes: []map[string]any{{
+			"name":       "planet",
+			"primitives": []map[string]any{{"attributes": map[string]int{"POSITION": 0}, "mode": 0}},
+		}},
+		Accessors: []map[string]any{{
+			"bufferView": 0, "componentType": 5126, "count": 1, "type": "VEC3",
+			"min": []float64{0, 0, 0}, "max": []float64{0, 0, 0},
+		}},
+		BufferViews: []map[string]any{{"buffer": 0, "byteLength": 12}},
+		Buffers:     []map[string]any{{"byteLength": 12, "uri": gltfPoint}},
+	}
+	for i, p := range planets {
+		pos := opts.Axes.Convert(p.Coordinates)
+		doc.Nodes = append(doc.Nodes, gltfNode{
+			Name:        p.Name,
+			Translation: [3]float64{pos[0] * scale, pos[1] * scale, pos[2] * scale},
+			Extras:      gltfExtras{Universe: p.Universe, Seed: p.Seed, BiomeType: p.BiomeType, Host: p.Host, Port: p.Port},
+		})
+		doc.Scenes[0].Nodes = append(doc.Scenes[0].Nodes, i)
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,166 @@
+package discover
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+)
+
+// --------- GODOT EXPORT ---------
+
+// AxisConvention says how the pods' coordinates map onto Godot's Y-up,
+// right-handed axes.
+type AxisConvention int
+
+const (
+	AxesYUp        AxisConvention = iota // already Y-up right-handed; unchanged (default)
+	AxesZUp                              // Z-up right-handed: (x, y, z) -> (x, z, -y)
+	AxesLeftHanded                       // Y-up left-handed, as in Unity: (x, y, z) -> (x, y, -z)
+)
+
+// Convert maps a point into Godot axes.
+func (a AxisConvention) Convert(v [3]float64) [3]float64 {
+	switch a {
+	case AxesZUp:
+		return [3]float64{v[0], v[2], -v[1]}
+	case AxesLeftHanded:
+		return [3]float64{v[0], v[1], -v[2]}
+	}
+	return v
+}
+
+// GodotOptions tunes ExportGodot.
+type GodotOptions struct {
+	Axes          AxisConvention
+	Scale         float64            // multiplies every position and radius; 0 means 1
+	DefaultRadius float64            // planet radius when Radii has no entry
+	Radii         map[string]float64 // per-planet radius, by name
+}
+
+// GodotScene is the layout written by ExportGodot. Transforms are 12 numbers
+// in Transform3D constructor order: the x, y and z basis columns, then the
+// origin, so a GDScript client can rebuild one with
+//
+//	var t = Transform3D(Vector3(a[0],a[1],a[2]), Vector3(a[3],a[4],a[5]), Vector3(a[6],a[7],a[8]), Vector3(a[9],a[10],a[11]))
+type GodotScene struct {
+	Format  string        `json:"format"` // "discover-godot"
+	Version int           `json:"version"`
+	Planets []GodotPlanet `json:"planets"`
+	Spawns  []GodotMarker `json:"spawn_markers"`
+}
+
+type GodotPlanet struct {
+	Name      string      `json:"name"`
+	Universe  string      `json:"universe,omitempty"`
+	Seed      int         `json:"seed"`
+	BiomeType int         `json:"biome_type"`
+	Radius    float64     `json:"radius"`
+	Transform [12]float64 `json:"transform"`
+}
+
+// GodotMarker is a spawn point whose -Z axis (Godot's forward) faces away
+// from its planet.
+type GodotMarker struct {
+	Planet    string      `json:"planet"`
+	Free      bool        `json:"free"`
+	Transform [12]float64 `json:"transform"`
+}
+
+const godotVersion = 1
+
+// GodotScene builds the Godot layout for the planets (sorted by name) and the
+// given spawn plans; nil plans are skipped.
+func (d *Discover) GodotScene(opts GodotOptions, plans ...*SpawnPlan) GodotScene {
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	at := func(v [3]float64) [3]float64 {
+		v = opts.Axes.Convert(v)
+		return [3]float64{v[0] * scale, v[1] * scale, v[2] * scale}
+	}
+
+	planets := d.PlanetList()
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+	scene := GodotScene{Format: "discover-godot", Version: godotVersion, Planets: []GodotPlanet{}, Spawns: []GodotMarker{}}
+	for _, p := range planets {
+		r, ok := opts.Radii[p.Name]
+		if !ok {
+			r = opts.DefaultRadius
+		}
+		scene.Planets = append(scene.Planets, GodotPlanet{
+			Name: p.Name, Universe: p.Universe, Seed: p.Seed, BiomeType: p.BiomeType,
+			Radius: r * scale, Transform: godotTransform(identityBasis, at(p.Coordinates)),
+		})
+	}
+	for _, plan := range plans {
+		if plan == nil {
+			continue
+		}
+		center := at(plan.Planet.Coordinates)
+		for _, pt := range plan.Points {
+			pos := at(vec3f(pt.Position))
+			out := [3]float64{pos[0] - center[0], pos[1] - center[1], pos[2] - center[2]}
+			scene.Spawns = append(scene.Spawns, GodotMarker{
+				Planet: plan.Planet.Name, Free: pt.Free,
+				Transform: godotTransform(facing(out), pos),
+			})
+		}
+	}
+	return scene
+}
+
+// ExportGodot writes GodotScene as indented JSON.
+func (d *Discover) ExportGodot(w io.Writer, opts GodotOptions, plans ...*SpawnPlan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.GodotScene(opts, plans...))
+}
+
+// basis holds the x, y and z axis columns.
+type basis [3][3]float64
+
+var identityBasis = basis{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+func godotTransform(b basis, origin [3]float64) [12]float64 {
+	var t [12]float64
+	copy(t[0:3], b[0][:])
+	copy(t[3:6], b[1][:])
+	copy(t[6:9], b[2][:])
+	copy(t[9:12], origin[:])
+	for i, v := range t {
+		if v == 0 {
+			t[i] = 0 // no -0 in the JSON
+		}
+	}
+	return t
+}
+
+// facing returns the basis whose -Z axis points along dir with Y kept as
+// close to up as possible, like Godot's Basis.looking_at.
+func facing(dir [3]float64) basis {
+	z := normalize3([3]float64{-dir[0], -dir[1], -dir[2]})
+	if z == ([3]float64{}) {
+		return identityBasis
+	}
+	up := [3]float64{0, 1, 0}
+	if math.Abs(z[1]) > 0.999 {
+		up = [3]float64{0, 0, 1} // dir is (anti)parallel to Y
+	}
+	x := normalize3(cross3(up, z))
+	y := cross3(z, x)
+	return basis{x, y, z}
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func normalize3(v [3]float64) [3]float64 {
+	n := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if n == 0 {
+		return [3]float64{}
+	}
+	return [3]float64{v[0] / n, v[1] / n, v[2] / n}
+}
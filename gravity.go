@@ -0,0 +1,105 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+)
+
+// --------- GRAVITY AND INFLUENCE ---------
+
+// MassFunc gives a planet's gravitational weight (see Config.PlanetMass).
+type MassFunc func(PlanetRecord) float64
+
+// planetMass is the weight of p under Config.PlanetMass.
+func (d *Discover) planetMass(p PlanetRecord) float64 {
+	if d.Config.PlanetMass == nil {
+		return 1
+	}
+	return d.Config.PlanetMass(p)
+}
+
+// GravityAt returns the summed inverse-square pull of every planet at point:
+// each contributes mass/r^2 along the direction toward it. A planet exactly at
+// point is skipped, since its pull has no direction.
+func (d *Discover) GravityAt(point []float64) []float64 {
+	q := vec3f(point)
+	var g [3]float64
+	for _, p := range d.PlanetList() {
+		v := [3]float64{p.Coordinates[0] - q[0], p.Coordinates[1] - q[1], p.Coordinates[2] - q[2]}
+		r2 := v[0]*v[0] + v[1]*v[1] + v[2]*v[2]
+		if r2 == 0 || !finite3(p.Coordinates) {
+			continue
+		}
+		f := d.planetMass(p) / (r2 * math.Sqrt(r2))
+		g[0] += v[0] * f
+		g[1] += v[1] * f
+		g[2] += v[2] * f
+	}
+	return g[:]
+}
+
+// DominantPlanet returns the planet whose influence (mass/r^2) at point is
+// strongest, and that influence; ties go to the first name. A planet exactly
+// at point dominates with +Inf. With no planets it returns ("", 0).
+func (d *Discover) DominantPlanet(point []float64) (string, float64) {
+	q := vec3f(point)
+	best, bestF := "", 0.0
+	for _, p := range d.PlanetList() {
+		if !finite3(p.Coordinates) {
+			continue
+		}
+		f := d.planetMass(p) / dist2(q, p.Coordinates)
+		if math.IsNaN(f) {
+			continue // weightless planet at point
+		}
+		if best == "" || f > bestF {
+			best, bestF = p.Name, f
+		}
+	}
+	return best, bestF
+}
+
+// --- staging points ---
+
+// StagingPoint is a candidate staging location between two planets.
+type StagingPoint struct {
+	Position []float64
+	Free     bool // IsSpawnPointFree(Position, minDist)
+}
+
+// Midpoint returns the point halfway between planets a and b, flagged free if
+// no planet is within minDist of it.
+func (d *Discover) Midpoint(a, b string, minDist float64) (StagingPoint, error) {
+	return d.stagingPoint(a, b, minDist, func(PlanetRecord, PlanetRecord) float64 { return 0.5 })
+}
+
+// BalancePoint returns the point on the line between planets a and b where
+// their pulls (Config.PlanetMass / r^2) cancel, a rough stand-in for the L1
+// Lagrange point: nearer the lighter planet. Flagged free as in Midpoint.
+func (d *Discover) BalancePoint(a, b string, minDist float64) (StagingPoint, error) {
+	return d.stagingPoint(a, b, minDist, func(pa, pb PlanetRecord) float64 {
+		sa, sb := math.Sqrt(math.Max(d.planetMass(pa), 0)), math.Sqrt(math.Max(d.planetMass(pb), 0))
+		if sa+sb == 0 {
+			return 0.5
+		}
+		return sa / (sa + sb)
+	})
+}
+
+// stagingPoint places a point the fraction t(a, b) of the way from a to b.
+func (d *Discover) stagingPoint(a, b string, minDist float64, t func(PlanetRecord, PlanetRecord) float64) (StagingPoint, error) {
+	pa, ok := d.LookupPlanet(a)
+	if !ok {
+		return StagingPoint{}, fmt.Errorf("planet %s not found", a)
+	}
+	pb, ok := d.LookupPlanet(b)
+	if !ok {
+		return StagingPoint{}, fmt.Errorf("planet %s not found", b)
+	}
+	f := t(pa, pb)
+	pos := make([]float64, 3)
+	for i := range pos {
+		pos[i] = pa.Coordinates[i] + f*(pb.Coordinates[i]-pa.Coordinates[i])
+	}
+	return StagingPoint{Position: pos, Free: d.IsSpawnPointFree(pos, minDist)}, nil
+}
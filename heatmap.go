@@ -0,0 +1,122 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// --------- DENSITY HEATMAP ---------
+
+// DensityCell is one cube of a Heatmap.
+type DensityCell struct {
+	Index    [3]int     // cell coordinates: floor(position / CellSize) per axis
+	Min, Max [3]float64 // cell corners
+	Count    int
+	Density  float64 // planets per unit volume
+}
+
+// Heatmap counts planets per cube of a regular grid aligned to the origin.
+// Only occupied cells are stored; Lo and Hi bound the cells spanned by the
+// planets at the time it was built.
+type Heatmap struct {
+	CellSize float64
+	Lo, Hi   [3]int // inclusive cell index range
+	Counts   map[[3]int]int
+}
+
+// Heatmap buckets the planets into cubes of side cellSize, for finding
+// crowded and empty regions. A cellSize <= 0 gives an empty heatmap.
+func (d *Discover) Heatmap(cellSize float64) *Heatmap {
+	h := &Heatmap{CellSize: cellSize, Counts: make(map[[3]int]int)}
+	if cellSize <= 0 {
+		return h
+	}
+	for i, e := range d.planetIndex().pts {
+		c := h.index(e.p)
+		if i == 0 {
+			h.Lo, h.Hi = c, c
+		}
+		for a := range c {
+			h.Lo[a], h.Hi[a] = min(h.Lo[a], c[a]), max(h.Hi[a], c[a])
+		}
+		h.Counts[c]++
+	}
+	return h
+}
+
+func (h *Heatmap) index(p [3]float64) [3]int {
+	var c [3]int
+	for a := range c {
+		c[a] = int(math.Floor(p[a] / h.CellSize))
+	}
+	return c
+}
+
+func (h *Heatmap) cell(c [3]int) DensityCell {
+	out := DensityCell{Index: c, Count: h.Counts[c], Density: float64(h.Counts[c]) / (h.CellSize * h.CellSize * h.CellSize)}
+	for a := range c {
+		out.Min[a] = float64(c[a]) * h.CellSize
+		out.Max[a] = out.Min[a] + h.CellSize
+	}
+	return out
+}
+
+// At returns the cell containing point.
+func (h *Heatmap) At(point []float64) DensityCell {
+	return h.cell(h.index(vec3f(point)))
+}
+
+// Cells returns the occupied cells, most crowded first (ties by index).
+func (h *Heatmap) Cells() []DensityCell {
+	out := make([]DensityCell, 0, len(h.Counts))
+	for c := range h.Counts {
+		out = append(out, h.cell(c))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return lessIndex(out[i].Index, out[j].Index)
+	})
+	return out
+}
+
+// MaxEmptyCells caps the Lo..Hi box EmptyCells will walk.
+const MaxEmptyCells = 1 << 22
+
+// EmptyCells returns the cells between Lo and Hi holding no planets, in index
+// order: gaps inside the discovered world. It returns an error instead when
+// the box spans more than MaxEmptyCells cells; use a larger cellSize.
+func (h *Heatmap) EmptyCells() ([]DensityCell, error) {
+	var out []DensityCell
+	if len(h.Counts) == 0 {
+		return out, nil
+	}
+	span := 1.0
+	for a := range h.Lo {
+		span *= float64(h.Hi[a]) - float64(h.Lo[a]) + 1
+	}
+	if span > MaxEmptyCells {
+		return nil, fmt.Errorf("heatmap spans %.3g cells, more than %d; use a larger cell size", span, MaxEmptyCells)
+	}
+	for x := h.Lo[0]; x <= h.Hi[0]; x++ {
+		for y := h.Lo[1]; y <= h.Hi[1]; y++ {
+			for z := h.Lo[2]; z <= h.Hi[2]; z++ {
+				if c := [3]int{x, y, z}; h.Counts[c] == 0 {
+					out = append(out, h.cell(c))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func lessIndex(a, b [3]int) bool {
+	for k := range a {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
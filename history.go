@@ -0,0 +1,150 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------- SNAPSHOT HISTORY ---------
+
+// Past scans are kept in the instance's state store as snapshots under
+// "history/<time>". MemoryStore (the default) loses them on exit; KVStore
+// keeps them in one embedded database file, FileStore in a file each.
+const historyPrefix = "history/"
+
+// historyKeyLayout is fixed-width UTC so keys sort in time order.
+const historyKeyLayout = "20060102T150405.000000000Z"
+
+func historyKey(t time.Time) string {
+	return historyPrefix + t.UTC().Format(historyKeyLayout)
+}
+
+// SaveHistory stores the current state as a snapshot keyed by the time it was
+// taken, and returns that time. With Config.SnapshotHistory set, ScanAll
+// calls it after every scan.
+func (d *Discover) SaveHistory() (time.Time, error) {
+	d.mu.Lock()
+	s := d.snapshotLocked()
+	d.mu.Unlock()
+	var buf bytes.Buffer
+	if err := encodeSnapshot(&buf, s, d.Config.HistoryEncoding); err != nil {
+		return time.Time{}, err
+	}
+	if err := d.store().Put(historyKey(s.TakenAt), buf.Bytes()); err != nil {
+		return time.Time{}, fmt.Errorf("history %s: %w", s.TakenAt.Format(time.RFC3339), err)
+	}
+	if _, err := d.PruneHistory(); err != nil {
+		return s.TakenAt, err
+	}
+	return s.TakenAt, nil
+}
+
+// HistoryRuns lists the times of the stored snapshots, oldest first.
+func (d *Discover) HistoryRuns() ([]time.Time, error) {
+	keys, err := d.store().List(historyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]time.Time, 0, len(keys))
+	for _, k := range keys {
+		t, err := time.Parse(historyKeyLayout, strings.TrimPrefix(k, historyPrefix))
+		if err != nil {
+			continue // not written by SaveHistory
+		}
+		runs = append(runs, t)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Before(runs[j]) })
+	return runs, nil
+}
+
+// HistorySnapshot reads the snapshot stored for a time returned by HistoryRuns.
+func (d *Discover) HistorySnapshot(at time.Time) (Snapshot, error) {
+	var s Snapshot
+	data, err := d.store().Get(historyKey(at))
+	if err != nil {
+		return s, fmt.Errorf("history %s: %w", at.UTC().Format(time.RFC3339Nano), err)
+	}
+	if s, err = decodeSnapshot(bytes.NewReader(data)); err != nil {
+		return s, fmt.Errorf("history %s: %w", at.UTC().Format(time.RFC3339Nano), err)
+	}
+	return s, nil
+}
+
+// LoadHistory replaces the current state with a past snapshot, as LoadSnapshot
+// does.
+func (d *Discover) LoadHistory(at time.Time) error {
+	s, err := d.HistorySnapshot(at)
+	if err != nil {
+		return err
+	}
+	return d.restoreSnapshot(s)
+}
+
+// StateAt returns the state as of t: the newest stored snapshot taken at or
+// before t.
+func (d *Discover) StateAt(t time.Time) (Snapshot, error) {
+	runs, err := d.HistoryRuns()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	i := sort.Search(len(runs), func(i int) bool { return runs[i].After(t) })
+	if i == 0 {
+		return Snapshot{}, fmt.Errorf("history: no snapshot at or before %s", t.UTC().Format(time.RFC3339))
+	}
+	return d.HistorySnapshot(runs[i-1])
+}
+
+// --- retention ---
+
+// Retention bounds the stored history. Snapshots beyond MaxCount (oldest
+// first) or older than MaxAge are pruned after every SaveHistory; zero
+// fields don't limit. The newest snapshot is always kept.
+type Retention struct {
+	MaxCount int
+	MaxAge   time.Duration
+}
+
+// PruneHistory applies Config.HistoryRetention now and returns how many
+// snapshots it deleted.
+func (d *Discover) PruneHistory() (int, error) {
+	keep := d.Config.HistoryRetention
+	if keep.MaxCount <= 0 && keep.MaxAge <= 0 {
+		return 0, nil
+	}
+	runs, err := d.HistoryRuns()
+	if err != nil {
+		return 0, err
+	}
+	drop := 0
+	if keep.MaxCount > 0 && len(runs) > keep.MaxCount {
+		drop = len(runs) - keep.MaxCount
+	}
+	if keep.MaxAge > 0 {
+		cutoff := time.Now().Add(-keep.MaxAge)
+		for drop < len(runs)-1 && runs[drop].Before(cutoff) {
+			drop++
+		}
+	}
+	st := d.store()
+	for i, t := range runs[:drop] {
+		if err := st.Delete(historyKey(t)); err != nil {
+			return i, fmt.Errorf("history %s: %w", t.Format(time.RFC3339Nano), err)
+		}
+	}
+	return drop, nil
+}
+
+// recordHistory is ScanAll's hook for Config.SnapshotHistory.
+func (d *Discover) recordHistory() {
+	if !d.Config.SnapshotHistory {
+		return
+	}
+	if _, err := d.SaveHistory(); err != nil {
+		d.mu.Lock()
+		d.emitWarningsLocked(PodResult{Warnings: []Warning{{Kind: WarnStorage, Command: "save_history", Message: err.Error()}}})
+		d.mu.Unlock()
+	}
+}
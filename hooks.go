@@ -0,0 +1,130 @@
+package discover
+
+import "sort"
+
+// --------- EVENT HOOKS ---------
+
+// Hooks run synchronously on the scanning goroutine after ScanAll or
+// RescanPods (or Merge, for the discovery hooks) has merged its results and
+// released the lock, so they may call back into the Discover. A slow hook
+// delays the scan's return.
+type hooks struct {
+	planet    []func(PlanetRecord)
+	cube      []func(CubeRecord)
+	podFailed []func(PodResult)
+	scanDone  []func([]PodResult)
+}
+
+func (h *hooks) empty() bool {
+	return len(h.planet) == 0 && len(h.cube) == 0 && len(h.podFailed) == 0 && len(h.scanDone) == 0
+}
+
+// OnPlanetDiscovered registers fn for every Planets entry that a scan or
+// merge adds, i.e. a name that wasn't known before it.
+func (d *Discover) OnPlanetDiscovered(fn func(PlanetRecord)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks.planet = append(d.hooks.planet, fn)
+}
+
+// OnCubeDiscovered registers fn for every cube name a scan or merge adds.
+func (d *Discover) OnCubeDiscovered(fn func(CubeRecord)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks.cube = append(d.hooks.cube, fn)
+}
+
+// OnPodFailed registers fn for every unsuccessful pod result, quarantined
+// pods included.
+func (d *Discover) OnPodFailed(fn func(PodResult)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks.podFailed = append(d.hooks.podFailed, fn)
+}
+
+// OnScanComplete registers fn to receive the results of every ScanAll and
+// RescanPods, after the other hooks have run.
+func (d *Discover) OnScanComplete(fn func([]PodResult)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks.scanDone = append(d.hooks.scanDone, fn)
+}
+
+// knownNames is what was known before a merge; nil when no hooks are set.
+type knownNames struct {
+	planets, cubes map[string]bool
+}
+
+// knownLocked records the current planet and cube names. d.mu must be held.
+func (d *Discover) knownLocked() *knownNames {
+	if d.hooks.empty() {
+		return nil
+	}
+	k := &knownNames{planets: make(map[string]bool, len(d.Planets)), cubes: make(map[string]bool, len(d.Cubes))}
+	for name := range d.Planets {
+		k.planets[name] = true
+	}
+	for name := range d.Cubes {
+		k.cubes[name] = true
+	}
+	return k
+}
+
+// fireHooks runs the hooks for a scan whose merge started from before. d.mu
+// must not be held.
+func (d *Discover) fireHooks(before *knownNames, results []PodResult) {
+	if before == nil {
+		return
+	}
+	d.fireDiscoveredHooks(before)
+	d.mu.Lock()
+	h := d.hooks
+	d.mu.Unlock()
+	for _, r := range results {
+		if r.Success {
+			continue
+		}
+		for _, fn := range h.podFailed {
+			fn(r)
+		}
+	}
+	for _, fn := range h.scanDone {
+		fn(results)
+	}
+}
+
+// fireDiscoveredHooks runs the planet and cube hooks for the names added
+// since before. d.mu must not be held.
+func (d *Discover) fireDiscoveredHooks(before *knownNames) {
+	if before == nil {
+		return
+	}
+	d.mu.Lock()
+	h := d.hooks
+	var planets []PlanetRecord
+	var cubes []CubeRecord
+	for name, p := range d.Planets {
+		if !before.planets[name] {
+			planets = append(planets, p)
+		}
+	}
+	for name, c := range d.Cubes {
+		if !before.cubes[name] {
+			cubes = append(cubes, c)
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+	sort.Slice(cubes, func(i, j int) bool { return cubes[i].Name < cubes[j].Name })
+
+	for _, p := range planets {
+		for _, fn := range h.planet {
+			fn(p)
+		}
+	}
+	for _, c := range cubes {
+		for _, fn := range h.cube {
+			fn(c)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package discover
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --------- EMBEDDED KEY-VALUE STORE ---------
+//
+// KVStore is an embedded key-value database in a single file, for keeping
+// snapshot history across restarts without a file per snapshot or an
+// external server. The file is an append-only log of records
+//
+//	op(1) keyLen(uvarint) key valueLen(uvarint) value crc32(4, big-endian)
+//
+// where op is kvPut or kvDelete and the CRC covers everything before it. The
+// index of live values is rebuilt from the log on open; a torn or corrupt
+// tail left by a crash is cut off. Space taken by overwritten and deleted
+// values is reclaimed by Compact, which also runs by itself once the dead
+// records outweigh the live ones.
+
+const (
+	kvPut    byte = 1
+	kvDelete byte = 2
+)
+
+// kvAutoCompact is the least dead space Delete and Put compact away on
+// their own.
+const kvAutoCompact = 1 << 20
+
+type kvEntry struct {
+	off  int64 // value offset in the file
+	size int
+}
+
+// KVStore is a StateStore backed by one embedded log file. It is safe for
+// concurrent use within a process; only one process may open the file at a
+// time. Every write is synced before it returns.
+type KVStore struct {
+	mu    sync.Mutex
+	path  string
+	f     *os.File
+	size  int64 // end of the last valid record
+	dead  int64 // bytes of records no longer live
+	index map[string]kvEntry
+}
+
+// OpenKVStore opens or creates the store file at path.
+func OpenKVStore(path string) (*KVStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &KVStore{path: path, f: f, index: make(map[string]kvEntry)}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("kv store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// replay rebuilds the index and truncates anything after the last valid
+// record.
+func (s *KVStore) replay() error {
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.f)
+	var off int64
+	for {
+		op, key, valOff, n, err := readKVRecord(r, off)
+		if err != nil {
+			break // end of log, or a torn tail
+		}
+		if old, ok := s.index[key]; ok {
+			s.dead += kvRecordSize(key, old.size)
+		}
+		switch op {
+		case kvPut:
+			s.index[key] = kvEntry{off: valOff, size: int(n - (valOff - off) - 4)}
+		case kvDelete:
+			delete(s.index, key)
+			s.dead += n
+		}
+		off += n
+	}
+	s.size = off
+	if st, err := s.f.Stat(); err != nil {
+		return err
+	} else if st.Size() != off {
+		if err := s.f.Truncate(off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readKVRecord reads one record starting at file offset off and returns its
+// op, key, the file offset of its value and its total length.
+func readKVRecord(r *bufio.Reader, off int64) (op byte, key string, valOff, n int64, err error) {
+	h := crc32.NewIEEE()
+	tr := io.TeeReader(r, h)
+	var hdr [1]byte
+	if _, err = io.ReadFull(tr, hdr[:]); err != nil {
+		return
+	}
+	op = hdr[0]
+	if op != kvPut && op != kvDelete {
+		return 0, "", 0, 0, errors.New("bad record")
+	}
+	keyLen, kn, err := readUvarint(tr)
+	if err != nil {
+		return
+	}
+	keyBuf := make([]byte, keyLen)
+	if _, err = io.ReadFull(tr, keyBuf); err != nil {
+		return
+	}
+	valLen, vn, err := readUvarint(tr)
+	if err != nil {
+		return
+	}
+	valOff = off + 1 + int64(kn) + int64(keyLen) + int64(vn)
+	if _, err = io.CopyN(io.Discard, tr, int64(valLen)); err != nil {
+		return
+	}
+	var sum [4]byte
+	if _, err = io.ReadFull(r, sum[:]); err != nil {
+		return
+	}
+	if binary.BigEndian.Uint32(sum[:]) != h.Sum32() {
+		return 0, "", 0, 0, errors.New("checksum mismatch")
+	}
+	n = valOff - off + int64(valLen) + 4
+	return op, string(keyBuf), valOff, n, nil
+}
+
+// readUvarint reads a uvarint and reports how many bytes it took.
+func readUvarint(r io.Reader) (uint64, int, error) {
+	var b [1]byte
+	var x uint64
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, 0, err
+		}
+		x |= uint64(b[0]&0x7f) << (7 * i)
+		if b[0] < 0x80 {
+			return x, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("bad varint")
+}
+
+func appendKVRecord(b []byte, op byte, key string, val []byte) []byte {
+	start := len(b)
+	b = append(b, op)
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	b = binary.AppendUvarint(b, uint64(len(val)))
+	b = append(b, val...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[start:]))
+}
+
+func kvRecordSize(key string, valLen int) int64 {
+	var tmp [binary.MaxVarintLen64]byte
+	return int64(1+binary.PutUvarint(tmp[:], uint64(len(key)))+len(key)+
+		binary.PutUvarint(tmp[:], uint64(valLen))+valLen) + 4
+}
+
+// write appends one record and syncs it. s.mu must be held.
+func (s *KVStore) write(op byte, key string, val []byte) (kvEntry, error) {
+	if s.f == nil {
+		return kvEntry{}, errors.New("kv store closed")
+	}
+	rec := appendKVRecord(nil, op, key, val)
+	if _, err := s.f.WriteAt(rec, s.size); err != nil {
+		s.f.Truncate(s.size)
+		return kvEntry{}, err
+	}
+	if err := s.f.Sync(); err != nil {
+		s.f.Truncate(s.size)
+		return kvEntry{}, err
+	}
+	e := kvEntry{off: s.size + int64(len(rec)-len(val)-4), size: len(val)}
+	s.size += int64(len(rec))
+	return e, nil
+}
+
+func (s *KVStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil, errors.New("kv store closed")
+	}
+	e, ok := s.index[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	b := make([]byte, e.size)
+	if _, err := s.f.ReadAt(b, e.off); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *KVStore) Put(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, err := s.write(kvPut, key, data)
+	if err != nil {
+		return err
+	}
+	if old, ok := s.index[key]; ok {
+		s.dead += kvRecordSize(key, old.size)
+	}
+	s.index[key] = e
+	return s.maybeCompact()
+}
+
+func (s *KVStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.index[key]
+	if !ok {
+		return nil
+	}
+	if _, err := s.write(kvDelete, key, nil); err != nil {
+		return err
+	}
+	delete(s.index, key)
+	s.dead += kvRecordSize(key, old.size) + kvRecordSize(key, 0)
+	return s.maybeCompact()
+}
+
+func (s *KVStore) List(prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.index {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Compact rewrites the file with only the live values, through a temp file
+// and rename.
+func (s *KVStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+func (s *KVStore) maybeCompact() error {
+	if s.dead < kvAutoCompact || s.dead < s.size-s.dead {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact does the work of Compact. s.mu must be held.
+func (s *KVStore) compact() error {
+	if s.f == nil {
+		return errors.New("kv store closed")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("kv store %s compact: %w", s.path, err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		return fail(err)
+	}
+	keys := make([]string, 0, len(s.index))
+	for k := range s.index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	index := make(map[string]kvEntry, len(keys))
+	w := bufio.NewWriter(tmp)
+	var off int64
+	for _, k := range keys {
+		e := s.index[k]
+		val := make([]byte, e.size)
+		if _, err := s.f.ReadAt(val, e.off); err != nil {
+			return fail(err)
+		}
+		rec := appendKVRecord(nil, kvPut, k, val)
+		if _, err := w.Write(rec); err != nil {
+			return fail(err)
+		}
+		index[k] = kvEntry{off: off + int64(len(rec)-len(val)-4), size: e.size}
+		off += int64(len(rec))
+	}
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fail(err)
+	}
+	s.f.Close()
+	s.f, s.index, s.size, s.dead = tmp, index, off, 0
+	return nil
+}
+
+// Close closes the file; the store can't be used afterwards.
+func (s *KVStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
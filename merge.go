@@ -0,0 +1,148 @@
+package discover
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// --------- FEDERATION ---------
+
+// Merge folds another Discover's world view into d, so results collected by
+// separate scanners (networks, regions) form one picture. A planet or cube
+// name that d holds from a different pod is resolved by strategy, with d's
+// record as the first: CollideFirstWins keeps ours, CollideLastWins takes
+// theirs, CollideNewest the one whose pod was scanned successfully more
+// recently, CollideMerge fills ours in from theirs and CollideNamespace keeps
+// both planets under NamespacedName. CollideError changes nothing and returns
+// a *PlanetConflictError. Cubes only follow CollideLastWins and
+// CollideNewest; the same name from the same pod always keeps the newer copy.
+// Results and Sessions are appended (skipping ones d already has), planet
+// labels are unioned and units take the more recently updated record. The
+// OnPlanetDiscovered and OnCubeDiscovered hooks run for the names added. It
+// returns the planet conflicts found, whatever the strategy.
+func (d *Discover) Merge(other *Discover, strategy CollisionStrategy) ([]PlanetConflict, error) {
+	if other == d {
+		return nil, errors.New("merge: cannot merge a Discover into itself")
+	}
+	other.mu.Lock()
+	s := other.snapshotLocked()
+	other.mu.Unlock()
+	return d.MergeSnapshot(s, strategy)
+}
+
+// MergeSnapshot is Merge for a snapshot, e.g. one received from a remote
+// scanner.
+func (d *Discover) MergeSnapshot(s Snapshot, strategy CollisionStrategy) ([]PlanetConflict, error) {
+	d.mu.Lock()
+	d.collided = nil // marks from the last scan; CollideNamespace below and the next scan rebuild them
+	ours, theirs := lastScanned(d.Results), lastScanned(s.Results)
+	newer := func(ourPod, theirPod PodRef) bool {
+		if ourPod == theirPod || strategy == CollideNewest {
+			return theirs[theirPod].After(ours[ourPod])
+		}
+		return strategy == CollideLastWins
+	}
+
+	var conflicts []PlanetConflict
+	for name, p := range s.Planets {
+		if old, ok := d.Planets[name]; ok && old.PodRef != p.PodRef {
+			conflicts = append(conflicts, PlanetConflict{Name: name, Pods: []PodRef{old.PodRef, p.PodRef}})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Name < conflicts[j].Name })
+	if strategy == CollideError && len(conflicts) > 0 {
+		d.mu.Unlock()
+		return conflicts, &PlanetConflictError{Conflicts: conflicts}
+	}
+	before := d.knownLocked()
+
+	mergePlanets := func(dst, src map[string]PlanetRecord, scope string) {
+		for name, p := range src {
+			old, ok := dst[name]
+			switch {
+			case !ok:
+				dst[name] = p
+			case old.PodRef != p.PodRef && strategy == CollideMerge:
+				dst[name] = mergePlanet(old, p)
+			case old.PodRef != p.PodRef && strategy == CollideNamespace:
+				delete(dst, name)
+				dst[NamespacedName(old)] = old
+				dst[NamespacedName(p)] = p
+				if d.collided == nil {
+					d.collided = make(map[string]bool)
+				}
+				d.collided[scope+"\x00"+name] = true
+			case newer(old.PodRef, p.PodRef):
+				dst[name] = p
+			}
+		}
+	}
+	mergePlanets(d.Planets, s.Planets, flatScope)
+	for u, byName := range s.UniversePlanets {
+		if d.UniversePlanets[u] == nil {
+			d.UniversePlanets[u] = make(map[string]PlanetRecord, len(byName))
+		}
+		mergePlanets(d.UniversePlanets[u], byName, u)
+	}
+	for name, c := range s.Cubes {
+		if old, ok := d.Cubes[name]; !ok || newer(old.PodRef, c.PodRef) {
+			d.Cubes[name] = c
+		}
+	}
+
+	type resultKey struct {
+		pod     PodRef
+		session string
+		at      time.Time
+	}
+	have := make(map[resultKey]bool, len(d.Results))
+	for _, r := range d.Results {
+		have[resultKey{r.PodRef, r.Session, r.ScannedAt}] = true
+	}
+	for _, r := range s.Results {
+		if !have[resultKey{r.PodRef, r.Session, r.ScannedAt}] {
+			d.Results = append(d.Results, r)
+		}
+	}
+	haveSession := make(map[string]bool, len(d.sessions))
+	for _, ss := range d.sessions {
+		haveSession[ss.ID+ss.StartedAt.String()] = true
+	}
+	for _, ss := range s.Sessions {
+		if !haveSession[ss.ID+ss.StartedAt.String()] {
+			d.sessions = append(d.sessions, ss)
+		}
+	}
+	sort.SliceStable(d.sessions, func(i, j int) bool { return d.sessions[i].StartedAt.Before(d.sessions[j].StartedAt) })
+
+	for name, l := range s.Labels {
+		mine := d.labels[name].clone()
+		mine.Tags = uniqueSorted(append(mine.Tags, l.Tags...))
+		for k, v := range l.Annotations {
+			if _, ok := mine.Annotations[k]; !ok {
+				if mine.Annotations == nil {
+					mine.Annotations = make(map[string]string)
+				}
+				mine.Annotations[k] = v
+			}
+		}
+		d.setLabelsLocked(name, mine)
+	}
+	d.units.merge(s.Units)
+	d.mu.Unlock()
+	d.invalidateChanged()
+	d.fireDiscoveredHooks(before)
+	return conflicts, nil
+}
+
+// lastScanned maps each pod to the time of its latest successful result.
+func lastScanned(results []PodResult) map[PodRef]time.Time {
+	out := make(map[PodRef]time.Time)
+	for _, r := range results {
+		if r.Success && r.ScannedAt.After(out[r.PodRef]) {
+			out[r.PodRef] = r.ScannedAt
+		}
+	}
+	return out
+}
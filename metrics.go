@@ -0,0 +1,271 @@
+package discover
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------- SCAN METRICS ---------
+
+// Metric names, in the Prometheus text exposition format.
+const (
+	MetricScanDuration = "discover_scan_duration_seconds" // histogram of ScanAll wall time
+	MetricDialSeconds  = "discover_pod_dial_seconds"      // histogram per pod: connect + auth
+	MetricPodScans     = "discover_pod_scans_total"       // counter per pod and result
+	MetricPlanets      = "discover_planets"               // gauge: entries in Planets
+	MetricCubes        = "discover_cubes"                 // gauge: entries in Cubes
+)
+
+// MetricBuckets are the histogram upper bounds in seconds (Prometheus' defaults).
+var MetricBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ScanResultType classifies a pod result for MetricPodScans: "ok",
+// "quarantined", "auth", "not_pod", "timeout", "refused", "request" (sending
+// a scan request failed), "reply" (a reply was unreadable) or "connect".
+func ScanResultType(r PodResult) string {
+	e := r.Error
+	switch {
+	case r.Success:
+		return "ok"
+	case e == errQuarantined.Error():
+		return "quarantined"
+	case e == errBadPassword.Error() || e == errAuthFailed.Error():
+		return "auth"
+	case e == errNotPod.Error():
+		return "not_pod"
+	case strings.HasSuffix(e, " req fail") || strings.HasSuffix(e, " encode fail"):
+		return "request"
+	case strings.HasSuffix(e, " parse fail") || strings.HasSuffix(e, " read fail"):
+		return "reply"
+	case strings.Contains(e, "timeout"):
+		return "timeout"
+	case strings.Contains(e, "refused"):
+		return "refused"
+	}
+	return "connect"
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	n      uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(MetricBuckets))
+	}
+	h.n++
+	h.sum += v
+	if i := sort.SearchFloat64s(MetricBuckets, v); i < len(MetricBuckets) {
+		h.counts[i]++
+	}
+}
+
+type scanMetrics struct {
+	mu    sync.Mutex
+	scan  histogram
+	dial  map[PodRef]*histogram
+	scans map[PodRef]map[string]uint64
+}
+
+// observeScan records one ScanAll and its pods.
+func (m *scanMetrics) observeScan(took time.Duration, results []PodResult) {
+	m.mu.Lock()
+	m.scan.observe(took.Seconds())
+	m.mu.Unlock()
+	m.observePods(results)
+}
+
+// observePods records pod scans. Quarantined pods are counted but not
+// dialed, so they have no dial sample.
+func (m *scanMetrics) observePods(results []PodResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dial == nil {
+		m.dial = make(map[PodRef]*histogram)
+		m.scans = make(map[PodRef]map[string]uint64)
+	}
+	for _, r := range results {
+		kind := ScanResultType(r)
+		if m.scans[r.PodRef] == nil {
+			m.scans[r.PodRef] = make(map[string]uint64)
+		}
+		m.scans[r.PodRef][kind]++
+		if kind == "quarantined" {
+			continue
+		}
+		h := m.dial[r.PodRef]
+		if h == nil {
+			h = &histogram{}
+			m.dial[r.PodRef] = h
+		}
+		h.observe(r.DialTime.Seconds())
+	}
+}
+
+// MetricSample is one exported series value. Histograms expand into their
+// _bucket (with an "le" label), _sum and _count series.
+type MetricSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricsCollector exposes a Discover's scan metrics. It serves the
+// Prometheus text format over HTTP and can write it anywhere; Samples
+// returns the same values for bridging into another registry (e.g. as
+// const metrics from a prometheus.Collector's Collect).
+type MetricsCollector struct {
+	d *Discover
+}
+
+// Collector returns the metrics collector for d.
+func (d *Discover) Collector() *MetricsCollector {
+	return &MetricsCollector{d: d}
+}
+
+type metricFamily struct {
+	name, kind, help string
+	samples          []MetricSample
+}
+
+func (c *MetricsCollector) families() []metricFamily {
+	c.d.mu.Lock()
+	planets, cubes := len(c.d.Planets), len(c.d.Cubes)
+	c.d.mu.Unlock()
+
+	m := &c.d.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scan := metricFamily{name: MetricScanDuration, kind: "histogram", help: "Wall time of ScanAll."}
+	scan.samples = histogramSamples(MetricScanDuration, nil, &m.scan)
+	dial := metricFamily{name: MetricDialSeconds, kind: "histogram", help: "Time to connect to and authenticate with a pod."}
+	scans := metricFamily{name: MetricPodScans, kind: "counter", help: "Pod scans by result type."}
+	for _, pod := range sortedPods(m.scans) {
+		if h := m.dial[pod]; h != nil {
+			dial.samples = append(dial.samples, histogramSamples(MetricDialSeconds, map[string]string{"pod": pod.String()}, h)...)
+		}
+		kinds := make([]string, 0, len(m.scans[pod]))
+		for k := range m.scans[pod] {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for _, k := range kinds {
+			scans.samples = append(scans.samples, MetricSample{Name: MetricPodScans, Labels: map[string]string{"pod": pod.String(), "result": k}, Value: float64(m.scans[pod][k])})
+		}
+	}
+	return []metricFamily{
+		scan, dial, scans,
+		{name: MetricPlanets, kind: "gauge", help: "Planets currently known.", samples: []MetricSample{{Name: MetricPlanets, Value: float64(planets)}}},
+		{name: MetricCubes, kind: "gauge", help: "Cubes currently known.", samples: []MetricSample{{Name: MetricCubes, Value: float64(cubes)}}},
+	}
+}
+
+func sortedPods(m map[PodRef]map[string]uint64) []PodRef {
+	pods := make([]PodRef, 0, len(m))
+	for p := range m {
+		pods = append(pods, p)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Host != pods[j].Host {
+			return pods[i].Host < pods[j].Host
+		}
+		return pods[i].Port < pods[j].Port
+	})
+	return pods
+}
+
+func histogramSamples(name string, labels map[string]string, h *histogram) []MetricSample {
+	with := func(k, v string) map[string]string {
+		out := map[string]string{k: v}
+		for lk, lv := range labels {
+			out[lk] = lv
+		}
+		return out
+	}
+	var out []MetricSample
+	var cum uint64
+	for i, le := range MetricBuckets {
+		if h.counts != nil {
+			cum += h.counts[i]
+		}
+		out = append(out, MetricSample{Name: name + "_bucket", Labels: with("le", strconv.FormatFloat(le, 'g', -1, 64)), Value: float64(cum)})
+	}
+	out = append(out,
+		MetricSample{Name: name + "_bucket", Labels: with("le", "+Inf"), Value: float64(h.n)},
+		MetricSample{Name: name + "_sum", Labels: labels, Value: h.sum},
+		MetricSample{Name: name + "_count", Labels: labels, Value: float64(h.n)},
+	)
+	return out
+}
+
+// Samples returns every current series value.
+func (c *MetricsCollector) Samples() []MetricSample {
+	var out []MetricSample
+	for _, f := range c.families() {
+		out = append(out, f.samples...)
+	}
+	return out
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (c *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, f := range c.families() {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.samples {
+			b.WriteString(s.Name)
+			writeLabels(&b, s.Labels)
+			b.WriteByte(' ')
+			b.WriteString(formatMetric(s.Value))
+			b.WriteByte('\n')
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves WriteTo, for mounting at /metrics.
+func (c *MetricsCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabels(b *strings.Builder, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, `%s="%s"`, k, labelEscaper.Replace(labels[k]))
+	}
+	b.WriteByte('}')
+}
+
+func formatMetric(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
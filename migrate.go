@@ -0,0 +1,197 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// --------- PLANET MIGRATION ---------
+
+// Pod commands used by MigratePlanet. The planet itself is read from the source
+// pod's get_planets reply and sent unchanged.
+//
+//	-> {"type":"create_planet","universe":"...","planet":{...full Planet...}}
+//	-> {"type":"delete_planet","universe":"...","planet_name":"..."}
+//
+// Either pod may answer {"error":"..."} to refuse.
+const (
+	CmdCreatePlanet = "create_planet"
+	CmdDeletePlanet = "delete_planet"
+)
+
+type createPlanetRequest struct {
+	Type     string `json:"type"`
+	Universe string `json:"universe,omitempty"`
+	Planet   Planet `json:"planet"`
+}
+
+type deletePlanetRequest struct {
+	Type       string `json:"type"`
+	Universe   string `json:"universe,omitempty"`
+	PlanetName string `json:"planet_name"`
+}
+
+// MigratePlanet moves a planet from one pod to another, for rebalancing worlds
+// off a host being decommissioned. It exports the planet from fromPod, creates
+// it on toPod, checks that toPod now reports it with the same seed and
+// position, and only then deletes it from fromPod. Both pods are rescanned
+// afterwards so Planets points at the new owner. A toPod that already has a
+// planet of that name is refused up front, and if creation or verification
+// fails, fromPod is left untouched.
+func (d *Discover) MigratePlanet(name string, fromPod, toPod PodRef) error {
+	if fromPod == toPod {
+		return fmt.Errorf("planet %s: source and destination are both %s", name, fromPod)
+	}
+	d.mu.Lock()
+	universe := d.Planets[name].Universe
+	d.mu.Unlock()
+
+	planet, universe, err := fetchPlanet(d.Config, fromPod, name, universe)
+	if err != nil {
+		return err
+	}
+	if _, _, err := fetchPlanet(d.Config, toPod, name, universe); err == nil {
+		return fmt.Errorf("planet %s already exists on %s", name, toPod)
+	}
+	if err := podAck(d.Config, toPod, createPlanetRequest{Type: CmdCreatePlanet, Universe: universe, Planet: planet}); err != nil {
+		return fmt.Errorf("planet %s create on %s: %w", name, toPod, err)
+	}
+	moved, _, err := fetchPlanet(d.Config, toPod, name, universe)
+	if err != nil {
+		return fmt.Errorf("planet %s verify on %s: %w", name, toPod, err)
+	}
+	if moved.Seed != planet.Seed || vec3(moved.Position) != vec3(planet.Position) {
+		return fmt.Errorf("planet %s verify on %s: reported with seed %d at %v, sent seed %d at %v", name, toPod, moved.Seed, moved.Position, planet.Seed, planet.Position)
+	}
+	if err := podAck(d.Config, fromPod, deletePlanetRequest{Type: CmdDeletePlanet, Universe: universe, PlanetName: name}); err != nil {
+		return fmt.Errorf("planet %s delete on %s (now on both pods): %w", name, fromPod, err)
+	}
+	d.RescanPods(fromPod, toPod)
+	return nil
+}
+
+// fetchPlanet returns the full planet record a pod reports under name,
+// searching universe (if known) or every universe otherwise.
+func fetchPlanet(cfg Config, pod PodRef, name, universe string) (Planet, string, error) {
+	pc, err := dialPod(cfg, pod)
+	if err != nil {
+		return Planet{}, "", err
+	}
+	defer pc.Close()
+	var planets PlanetsResponse
+	if err := callCommandStream(pc, StepPlanets, PlanetsRequest{Universe: universe}, &planets); err != nil {
+		return Planet{}, "", err
+	}
+	for u, ps := range planets {
+		if u == "" {
+			u = universe
+		}
+		for _, p := range ps {
+			if p.Name == name && (universe == "" || u == universe) {
+				return p, u, nil
+			}
+		}
+	}
+	return Planet{}, "", fmt.Errorf("planet %s not found on %s", name, pod)
+}
+
+// podAck sends a command and treats a reply carrying "error" as a refusal.
+func podAck(cfg Config, pod PodRef, payload any) error {
+	raw, err := sendPodCommand(cfg, pod, payload)
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+		return fmt.Errorf("unexpected reply %q", raw)
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("pod refused: %s", reply.Error)
+	}
+	return nil
+}
+
+// --- units ---
+
+// MigrateUnit moves a registered unit to the pod at toHost:toPort, e.g. to
+// follow its planet after MigratePlanet. It reads the unit's state from its
+// pod, despawns it there and spawns it on the destination with the same
+// position and rotation (the pod default if none was reported); velocity is
+// not carried over. If the spawn fails and the destination doesn't report
+// the cube either, the unit is respawned on its old pod, and the error says
+// whether that worked. Cubes and the registry only change once the unit is on
+// the destination, in one step. While migrating, the unit is reserved:
+// MoveCube, DespawnEverywhere and other migrations of it fail.
+func (d *Discover) MigrateUnit(id, toHost string, toPort int) error {
+	toPod := PodRef{Host: toHost, Port: toPort}
+	u, release, err := d.units.claim(id)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if u.Pod == toPod {
+		return fmt.Errorf("unit %s is already on %s", id, toPod)
+	}
+
+	ctx := context.Background()
+	from, to := d.Client(u.Pod), d.Client(toPod)
+	state, err := from.GetCubeStateContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unit %s state on %s: %w", id, u.Pod, err)
+	}
+	pos, rot := state.Position.Slice(), []float64(nil)
+	if state.HasRotation() {
+		rot = state.Rotation.Slice()
+	}
+	if err := from.DespawnCube(id); err != nil {
+		return fmt.Errorf("unit %s despawn on %s: %w", id, u.Pod, err)
+	}
+	if err := to.SpawnCube(id, pos, rot); err != nil {
+		// A spawn that timed out may still have happened.
+		if _, stateErr := to.GetCubeStateContext(ctx, id); stateErr != nil {
+			err = fmt.Errorf("unit %s spawn on %s: %w", id, toPod, err)
+			if rbErr := from.SpawnCube(id, pos, rot); rbErr != nil {
+				d.forgetCube(id, u.Pod)
+				d.units.despawned(id, u.Pod)
+				return errors.Join(err, fmt.Errorf("unit %s respawn on %s failed, unit lost: %w", id, u.Pod, rbErr))
+			}
+			return fmt.Errorf("%w (rolled back to %s)", err, u.Pod)
+		}
+	}
+
+	planet := d.podPlanet(toPod, state.Position)
+	d.mu.Lock()
+	rec, ok := d.Cubes[id]
+	if !ok {
+		rec = CubeRecord{Name: id}
+	}
+	d.forgetCubeLocked(id, u.Pod)
+	rec.PodRef = toPod
+	if d.Cubes == nil {
+		d.Cubes = make(map[string]CubeRecord)
+	}
+	d.Cubes[id] = rec
+	d.units.migrated(id, toPod, planet, state.Position)
+	d.mu.Unlock()
+	return nil
+}
+
+// podPlanet returns the planet on pod nearest to p, or "" if pod hosts none.
+func (d *Discover) podPlanet(pod PodRef, p Vec3) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	best, bestD := "", 0.0
+	for name, planet := range d.Planets {
+		if planet.PodRef != pod {
+			continue
+		}
+		if dist := p.Distance(planet.Coordinates); best == "" || dist < bestD || (dist == bestD && name < best) {
+			best, bestD = name, dist
+		}
+	}
+	return best
+}
@@ -0,0 +1,651 @@
+package discover
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --------- MESSAGEPACK ---------
+
+// Snapshots are written with the same field names, omitempty rules and time
+// format as their JSON form, in a more compact encoding. Structs are walked
+// directly rather than through encoding/json, so floats are written natively
+// and NaN or ±Inf coordinates kept by CoordKeep survive a round trip.
+// Integral floats are written as integers; map keys are written sorted.
+
+func encodeMsgpackSnapshot(w io.Writer, s Snapshot) error {
+	b, err := appendMsgpackValue(nil, reflect.ValueOf(s))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func decodeMsgpackSnapshot(data []byte) (Snapshot, error) {
+	var s Snapshot
+	v, rest, err := readMsgpack(data)
+	if err != nil {
+		return s, err
+	}
+	if len(rest) != 0 {
+		return s, errors.New("msgpack: trailing data")
+	}
+	err = assignMsgpack(reflect.ValueOf(&s).Elem(), v)
+	return s, err
+}
+
+// appendMsgpack encodes a value produced by json.Decoder with UseNumber.
+func appendMsgpack(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+	case string:
+		return appendMsgpackString(b, v), nil
+	case []any:
+		b = appendMsgpackLen(b, len(v), 0x90, 0xdc)
+		var err error
+		for _, e := range v {
+			if b, err = appendMsgpack(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackLen(b, len(v), 0x80, 0xde)
+		var err error
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			if b, err = appendMsgpack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackLen writes an array or map header: fix is the fixarray or
+// fixmap prefix, wide the 16-bit form (the 32-bit form follows it).
+func appendMsgpackLen(b []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+}
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+// readMsgpack decodes one value into JSON-compatible Go values.
+func readMsgpack(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errMsgpackShort
+	}
+	c, b := b[0], b[1:]
+	need := func(n int) ([]byte, error) {
+		if len(b) < n {
+			return nil, errMsgpackShort
+		}
+		return b[:n], nil
+	}
+	size := func(width int) (int, error) {
+		p, err := need(width)
+		if err != nil {
+			return 0, err
+		}
+		b = b[width:]
+		switch width {
+		case 1:
+			return int(p[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(p)), nil
+		}
+		return int(binary.BigEndian.Uint32(p)), nil
+	}
+	str := func(n int) (any, []byte, error) {
+		p, err := need(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		return string(p), b[n:], nil
+	}
+	num := func(n int) (uint64, error) {
+		p, err := need(n)
+		if err != nil {
+			return 0, err
+		}
+		b = b[n:]
+		var u uint64
+		for _, x := range p {
+			u = u<<8 | uint64(x)
+		}
+		return u, nil
+	}
+
+	switch {
+	case c < 0x80:
+		return int64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		return str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return readMsgpackArray(b, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return readMsgpackMap(b, int(c&0x0f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6: // str and bin
+		width := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}[c]
+		n, err := size(width)
+		if err != nil {
+			return nil, nil, err
+		}
+		return str(n)
+	case 0xdc, 0xdd:
+		n, err := size(map[byte]int{0xdc: 2, 0xdd: 4}[c])
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackArray(b, n)
+	case 0xde, 0xdf:
+		n, err := size(map[byte]int{0xde: 2, 0xdf: 4}[c])
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackMap(b, n)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := num(1 << (c - 0xcc))
+		if err != nil {
+			return nil, nil, err
+		}
+		if u > math.MaxInt64 {
+			return float64(u), b, nil
+		}
+		return int64(u), b, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		width := 1 << (c - 0xd0)
+		u, err := num(width)
+		if err != nil {
+			return nil, nil, err
+		}
+		shift := 64 - 8*width
+		return int64(u<<shift) >> shift, b, nil
+	case 0xca:
+		u, err := num(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return float64(math.Float32frombits(uint32(u))), b, nil
+	case 0xcb:
+		u, err := num(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(u), b, nil
+	}
+	return nil, nil, fmt.Errorf("msgpack: unsupported type byte 0x%02x", c)
+}
+
+func readMsgpackArray(b []byte, n int) (any, []byte, error) {
+	out := make([]any, 0, min(n, len(b)))
+	for i := 0; i < n; i++ {
+		v, rest, err := readMsgpack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, b = append(out, v), rest
+	}
+	return out, b, nil
+}
+
+func readMsgpackMap(b []byte, n int) (any, []byte, error) {
+	out := make(map[string]any, min(n, len(b)))
+	for i := 0; i < n; i++ {
+		k, rest, err := readMsgpack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("msgpack: map key is %T, want string", k)
+		}
+		v, rest, err := readMsgpack(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		out[key], b = v, rest
+	}
+	return out, b, nil
+}
+
+// --- struct walking ---
+
+var (
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+)
+
+// msgpackField is a struct field as encoding/json names it.
+type msgpackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var msgpackFieldCache sync.Map // reflect.Type -> []msgpackField
+
+// msgpackFields lists t's fields the way encoding/json does: json tags,
+// "-" skipped, untagged embedded structs flattened with shallower fields
+// winning, sorted by name.
+func msgpackFields(t reflect.Type) []msgpackField {
+	if f, ok := msgpackFieldCache.Load(t); ok {
+		return f.([]msgpackField)
+	}
+	byName := make(map[string]msgpackField)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		var embedded []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+				embedded = append(embedded, sf)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			if _, seen := byName[name]; !seen {
+				byName[name] = msgpackField{name: name, index: append(append([]int(nil), index...), i), omitEmpty: strings.Contains(opts, "omitempty")}
+			}
+		}
+		for _, sf := range embedded {
+			walk(sf.Type, append(append([]int(nil), index...), sf.Index...))
+		}
+	}
+	walk(t, nil)
+	fields := make([]msgpackField, 0, len(byName))
+	for _, f := range byName {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	msgpackFieldCache.Store(t, fields)
+	return fields
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// appendMsgpackValue encodes v as encoding/json would lay it out. Types with
+// their own MarshalJSON (time.Time, json.RawMessage) go through it.
+func appendMsgpackValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, 0xc0), nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		js, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.UseNumber()
+		var any any
+		if err := dec.Decode(&any); err != nil {
+			return nil, err
+		}
+		return appendMsgpack(b, any)
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return appendMsgpackValue(b, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgpackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); u > math.MaxInt64 {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), u), nil
+		}
+		return appendMsgpackInt(b, int64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return appendMsgpackInt(b, int64(f)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+	case reflect.String:
+		return appendMsgpackString(b, v.String()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendMsgpackString(b, base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		fallthrough
+	case reflect.Array:
+		b = appendMsgpackLen(b, v.Len(), 0x90, 0xdc)
+		var err error
+		for i := 0; i < v.Len(); i++ {
+			if b, err = appendMsgpackValue(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		keys := make([]string, 0, v.Len())
+		vals := make(map[string]reflect.Value, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			k, err := msgpackKey(it.Key())
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+			vals[k] = it.Value()
+		}
+		sort.Strings(keys)
+		b = appendMsgpackLen(b, len(keys), 0x80, 0xde)
+		var err error
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			if b, err = appendMsgpackValue(b, vals[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		present := make([]reflect.Value, 0, len(fields))
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			present, names = append(present, fv), append(names, f.name)
+		}
+		b = appendMsgpackLen(b, len(present), 0x80, 0xde)
+		var err error
+		for i, fv := range present {
+			b = appendMsgpackString(b, names[i])
+			if b, err = appendMsgpackValue(b, fv); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+func msgpackKey(k reflect.Value) (string, error) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("msgpack: unsupported map key type %s", k.Type())
+}
+
+// assignMsgpack stores a value from readMsgpack in dst, the inverse of
+// appendMsgpackValue. Types with their own UnmarshalJSON get the value as
+// JSON.
+func assignMsgpack(dst reflect.Value, v any) error {
+	if dst.CanAddr() && dst.Addr().Type().Implements(jsonUnmarshalerType) {
+		js, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return dst.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(js)
+	}
+	if v == nil {
+		dst.SetZero()
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("msgpack: cannot store %T in %s", v, dst.Type())
+	}
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignMsgpack(dst.Elem(), v)
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	case reflect.Bool:
+		x, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(x)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch x := v.(type) {
+		case int64:
+			dst.SetInt(x)
+		case float64:
+			dst.SetInt(int64(x))
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch x := v.(type) {
+		case int64:
+			dst.SetUint(uint64(x))
+		case float64:
+			dst.SetUint(uint64(x))
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch x := v.(type) {
+		case int64:
+			dst.SetFloat(float64(x))
+		case float64:
+			dst.SetFloat(x)
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.String:
+		x, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetString(x)
+		return nil
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			x, ok := v.(string)
+			if !ok {
+				return mismatch()
+			}
+			raw, err := base64.StdEncoding.DecodeString(x)
+			if err != nil {
+				return err
+			}
+			dst.SetBytes(raw)
+			return nil
+		}
+		items, ok := v.([]any)
+		if !ok {
+			return mismatch()
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		for i, item := range items {
+			if err := assignMsgpack(dst.Index(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		items, ok := v.([]any)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetZero()
+		for i := 0; i < min(len(items), dst.Len()); i++ {
+			if err := assignMsgpack(dst.Index(i), items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		t := dst.Type()
+		out := reflect.MakeMapWithSize(t, len(m))
+		for k, item := range m {
+			key := reflect.New(t.Key()).Elem()
+			switch key.Kind() {
+			case reflect.String:
+				key.SetString(k)
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n, err := strconv.ParseInt(k, 10, 64)
+				if err != nil {
+					return err
+				}
+				key.SetInt(n)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				n, err := strconv.ParseUint(k, 10, 64)
+				if err != nil {
+					return err
+				}
+				key.SetUint(n)
+			default:
+				return fmt.Errorf("msgpack: unsupported map key type %s", t.Key())
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := assignMsgpack(elem, item); err != nil {
+				return err
+			}
+			out.SetMapIndex(key, elem)
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		fields := msgpackFields(dst.Type())
+		for k, item := range m {
+			for _, f := range fields {
+				if f.name == k || strings.EqualFold(f.name, k) {
+					if err := assignMsgpack(dst.FieldByIndex(f.index), item); err != nil {
+						return fmt.Errorf("%s: %w", f.name, err)
+					}
+					break
+				}
+			}
+		}
+		return nil
+	}
+	return mismatch()
+}
@@ -0,0 +1,214 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- Request multiplexing ---
+//
+// With Config.Multiplex set, a PodClient tags every JSON command with a
+// "request_id" and lets any number of goroutines wait on the same connection.
+// A single reader goroutine routes replies back by the echoed request_id. A
+// reply without an ID is handed to the only outstanding request, if there is
+// exactly one, so pods that answer in order without echoing still work for
+// sequential callers.
+
+var (
+	errMuxClosed  = errors.New("multiplexed connection closed")
+	errMuxTimeout = errors.New("timed out waiting for reply")
+)
+
+type muxReply struct {
+	msg string
+	err error
+}
+
+type muxConn struct {
+	conn    msgTransport
+	timeout time.Duration
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]chan muxReply
+	closed  chan struct{}
+	err     error
+}
+
+func newMuxConn(conn msgTransport, timeout time.Duration) *muxConn {
+	m := &muxConn{
+		conn:    conn,
+		timeout: timeout,
+		pending: make(map[string]chan muxReply),
+		closed:  make(chan struct{}),
+	}
+	// The reader waits between replies indefinitely; per-request timeouts are
+	// enforced by callers. A read deadline firing mid-frame would lose the
+	// bytes already read and misroute every later reply.
+	conn.setTimeout(foreverTimeout)
+	go m.readLoop()
+	return m
+}
+
+func (m *muxConn) readLoop() {
+	for {
+		msg, err := m.conn.readMsg()
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		m.deliver(msg)
+	}
+}
+
+func (m *muxConn) deliver(msg string) {
+	id := replyID(msg)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch, ok := m.pending[id]
+	if !ok && id == "" && len(m.pending) == 1 {
+		for pid, pch := range m.pending {
+			id, ch, ok = pid, pch, true
+		}
+	}
+	if !ok {
+		return // late reply for a request that already gave up
+	}
+	delete(m.pending, id)
+	ch <- muxReply{msg: msg}
+}
+
+func (m *muxConn) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closed:
+		return
+	default:
+	}
+	m.err = err
+	close(m.closed)
+	for id, ch := range m.pending {
+		delete(m.pending, id)
+		ch <- muxReply{err: err}
+	}
+}
+
+func (m *muxConn) isClosed() bool {
+	select {
+	case <-m.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *muxConn) inFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pending)
+}
+
+// do sends msg tagged with a fresh request ID and waits for its reply.
+func (m *muxConn) do(ctx context.Context, msg string) (string, error) {
+	id := strconv.FormatUint(m.nextID.Add(1), 10)
+	tagged, err := tagRequest(msg, id)
+	if err != nil {
+		return "", err
+	}
+	ch := make(chan muxReply, 1)
+	m.mu.Lock()
+	if m.isClosed() {
+		m.mu.Unlock()
+		return "", errMuxClosed
+	}
+	m.pending[id] = ch
+	m.mu.Unlock()
+
+	m.writeMu.Lock()
+	err = m.conn.sendMsg(tagged)
+	m.writeMu.Unlock()
+	if err != nil {
+		m.fail(err)
+		return "", err
+	}
+
+	timer := time.NewTimer(m.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.msg, r.err
+	case <-ctx.Done():
+		m.forget(id)
+		return "", ctx.Err()
+	case <-timer.C:
+		m.forget(id)
+		return "", errMuxTimeout
+	}
+}
+
+func (m *muxConn) forget(id string) {
+	m.mu.Lock()
+	delete(m.pending, id)
+	m.mu.Unlock()
+}
+
+// tagRequest adds "request_id" to a JSON object command.
+func tagRequest(msg, id string) (string, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(msg), &fields); err != nil {
+		return "", errors.New("multiplexed commands must be JSON objects")
+	}
+	fields["request_id"], _ = json.Marshal(id)
+	out, err := json.Marshal(fields)
+	return string(out), err
+}
+
+// replyID extracts the echoed request_id, accepting string or number forms.
+func replyID(msg string) string {
+	var reply struct {
+		RequestID json.RawMessage `json:"request_id"`
+	}
+	if json.Unmarshal([]byte(msg), &reply) != nil || len(reply.RequestID) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(reply.RequestID, &s) == nil {
+		return s
+	}
+	return string(reply.RequestID)
+}
+
+// muxRoundTrip is roundTrip for multiplexed clients. The client lock is only
+// held while (re)connecting, so callers overlap on the wire.
+func (c *PodClient) muxRoundTrip(ctx context.Context, msg string) (string, error) {
+	c.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+	if c.mux != nil && c.mux.isClosed() {
+		c.dropLocked()
+	}
+	if c.mux == nil {
+		if err := c.connectLocked(ctx); err != nil {
+			c.mu.Unlock()
+			c.markSeen(false)
+			return "", err
+		}
+		c.mux = newMuxConn(c.conn, c.cfg.readTimeout())
+	}
+	m := c.mux
+	c.lastUsed = time.Now()
+	c.mu.Unlock()
+
+	resp, err := m.do(ctx, msg)
+	c.markSeen(err == nil)
+	return resp, err
+}
@@ -0,0 +1,126 @@
+package discover
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// --------- NAVIGATION ---------
+
+// NavGraph links every pair of planets no further apart than MaxJump.
+// It is a snapshot: rebuild it after scans that move or add planets.
+type NavGraph struct {
+	MaxJump float64
+	pos     map[string][3]float64
+	edges   map[string][]PlanetDistance // sorted by distance, then name
+}
+
+// Route is a path through a NavGraph.
+type Route struct {
+	Planets  []string // from first to last, both included
+	Distance float64  // total length of the jumps
+}
+
+// NavGraph builds the navigation graph over the current planets.
+func (d *Discover) NavGraph(maxJump float64) *NavGraph {
+	defer perfTrack("NavGraph")()
+	t := d.planetIndex()
+	g := &NavGraph{MaxJump: maxJump, pos: make(map[string][3]float64, len(t.pts)), edges: make(map[string][]PlanetDistance, len(t.pts))}
+	for i, e := range t.pts {
+		g.pos[e.name] = e.p
+		var out []PlanetDistance
+		t.within(e.p, maxJump*maxJump, true, func(j int, d2 float64) bool {
+			if j != i {
+				out = append(out, PlanetDistance{Name: t.pts[j].name, Distance: math.Sqrt(d2)})
+			}
+			return true
+		})
+		sort.Slice(out, func(a, b int) bool {
+			if out[a].Distance != out[b].Distance {
+				return out[a].Distance < out[b].Distance
+			}
+			return out[a].Name < out[b].Name
+		})
+		g.edges[e.name] = out
+	}
+	return g
+}
+
+// Neighbors returns the planets one jump from name, nearest first.
+func (g *NavGraph) Neighbors(name string) []PlanetDistance {
+	return append([]PlanetDistance(nil), g.edges[name]...)
+}
+
+// Route finds the shortest path from one planet to another with A*, using
+// straight-line distance as the heuristic.
+func (g *NavGraph) Route(from, to string) (Route, error) {
+	for _, name := range []string{from, to} {
+		if _, ok := g.pos[name]; !ok {
+			return Route{}, fmt.Errorf("planet %s not found", name)
+		}
+	}
+	goal := g.pos[to]
+	cost := map[string]float64{from: 0}
+	prev := map[string]string{}
+	open := &navQueue{{name: from, f: math.Sqrt(dist2(g.pos[from], goal))}}
+	done := map[string]bool{}
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(navItem).name
+		if cur == to {
+			r := Route{Distance: cost[to]}
+			for n := to; ; n = prev[n] {
+				r.Planets = append(r.Planets, n)
+				if n == from {
+					break
+				}
+			}
+			for i, j := 0, len(r.Planets)-1; i < j; i, j = i+1, j-1 {
+				r.Planets[i], r.Planets[j] = r.Planets[j], r.Planets[i]
+			}
+			return r, nil
+		}
+		if done[cur] {
+			continue
+		}
+		done[cur] = true
+		for _, e := range g.edges[cur] {
+			c := cost[cur] + e.Distance
+			if old, ok := cost[e.Name]; ok && c >= old {
+				continue
+			}
+			cost[e.Name], prev[e.Name] = c, cur
+			heap.Push(open, navItem{name: e.Name, f: c + math.Sqrt(dist2(g.pos[e.Name], goal))})
+		}
+	}
+	return Route{}, fmt.Errorf("no route from %s to %s within jump %g", from, to, g.MaxJump)
+}
+
+// Route is NavGraph(maxJump).Route(from, to), for one-off queries.
+func (d *Discover) Route(from, to string, maxJump float64) (Route, error) {
+	return d.NavGraph(maxJump).Route(from, to)
+}
+
+type navItem struct {
+	name string
+	f    float64 // cost so far + heuristic
+}
+
+type navQueue []navItem
+
+func (q navQueue) Len() int { return len(q) }
+func (q navQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].name < q[j].name
+}
+func (q navQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x any)   { *q = append(*q, x.(navItem)) }
+func (q *navQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
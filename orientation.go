@@ -0,0 +1,208 @@
+package discover
+
+import "math"
+
+// --------- ORIENTATION ---------
+
+// Objects are oriented in a right-handed frame with local +Y up and local -Z
+// forward (X right), the convention of Godot and OpenGL.
+
+// Quaternion is a unit rotation quaternion.
+type Quaternion struct{ X, Y, Z, W float64 }
+
+// Matrix3 is a row-major 3x3 rotation matrix; its columns are the rotated
+// local X, Y and Z axes.
+type Matrix3 [3][3]float64
+
+// IdentityQuaternion is no rotation.
+var IdentityQuaternion = Quaternion{W: 1}
+
+// SurfaceMatrix returns the rotation that stands an object at position on the
+// sphere around center: local up along the outward normal and forward along
+// forward projected onto the tangent plane. If forward is nil or parallel to
+// the normal, the projection of world -Z (or +X near the poles of that axis)
+// is used instead.
+func SurfaceMatrix(center, position, forward []float64) Matrix3 {
+	up := vec3f(OutwardNormal(center, position))
+	var f [3]float64
+	if len(forward) >= 3 {
+		f = tangent(vec3f(forward), up)
+	}
+	if f == ([3]float64{}) {
+		f = tangent([3]float64{0, 0, -1}, up)
+	}
+	if f == ([3]float64{}) {
+		f = tangent([3]float64{1, 0, 0}, up)
+	}
+	z := [3]float64{-f[0], -f[1], -f[2]}
+	x := cross3(up, z)
+	return Matrix3{
+		{x[0], up[0], z[0]},
+		{x[1], up[1], z[1]},
+		{x[2], up[2], z[2]},
+	}
+}
+
+// SurfaceOrientation is SurfaceMatrix as a quaternion.
+func SurfaceOrientation(center, position, forward []float64) Quaternion {
+	return SurfaceMatrix(center, position, forward).Quaternion()
+}
+
+// tangent returns v with its component along the unit normal n removed,
+// normalized; zero if v is (nearly) parallel to n.
+func tangent(v, n [3]float64) [3]float64 {
+	d := v[0]*n[0] + v[1]*n[1] + v[2]*n[2]
+	t := [3]float64{v[0] - d*n[0], v[1] - d*n[1], v[2] - d*n[2]}
+	if t[0]*t[0]+t[1]*t[1]+t[2]*t[2] < 1e-12 {
+		return [3]float64{}
+	}
+	return normalize3(t)
+}
+
+// Quaternion converts a rotation matrix to a unit quaternion.
+func (m Matrix3) Quaternion() Quaternion {
+	var q Quaternion
+	switch tr := m[0][0] + m[1][1] + m[2][2]; {
+	case tr > 0:
+		s := 2 * math.Sqrt(tr+1)
+		q = Quaternion{(m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s, s / 4}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = Quaternion{s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s, (m[2][1] - m[1][2]) / s}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = Quaternion{(m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s, (m[0][2] - m[2][0]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = Quaternion{(m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4, (m[1][0] - m[0][1]) / s}
+	}
+	return q.Normalize()
+}
+
+// Normalize returns q scaled to unit length; a zero q becomes the identity.
+func (q Quaternion) Normalize() Quaternion {
+	n := math.Sqrt(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)
+	if n == 0 {
+		return IdentityQuaternion
+	}
+	return Quaternion{q.X / n, q.Y / n, q.Z / n, q.W / n}
+}
+
+// Matrix converts q to a rotation matrix.
+func (q Quaternion) Matrix() Matrix3 {
+	x, y, z, w := q.X, q.Y, q.Z, q.W
+	return Matrix3{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// Rotate applies the rotation to v.
+func (m Matrix3) Rotate(v []float64) []float64 {
+	return []float64{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// Rotate applies the rotation to v.
+func (q Quaternion) Rotate(v []float64) []float64 {
+	return q.Matrix().Rotate(v)
+}
+
+// --- look-at and tangent frames ---
+
+// EulerAngles are rotations in degrees about local X (Pitch), Y (Yaw) and Z
+// (Roll), applied in YXZ order (roll first, yaw last) as in Godot.
+type EulerAngles struct{ Pitch, Yaw, Roll float64 }
+
+// Rotation is one orientation in every form clients tend to want.
+type Rotation struct {
+	Matrix     Matrix3
+	Quaternion Quaternion
+	Euler      EulerAngles
+}
+
+func newRotation(m Matrix3) Rotation {
+	return Rotation{Matrix: m, Quaternion: m.Quaternion(), Euler: m.Euler()}
+}
+
+// Euler decomposes the rotation into YXZ Euler angles. At pitch +-90 degrees
+// (gimbal lock) roll is reported as 0.
+func (m Matrix3) Euler() EulerAngles {
+	const deg = 180 / math.Pi
+	cb := math.Hypot(m[1][0], m[1][1]) // cos(pitch)
+	e := EulerAngles{Pitch: math.Atan2(-m[1][2], cb) * deg}
+	if cb > 1e-9 {
+		e.Yaw = math.Atan2(m[0][2], m[2][2]) * deg
+		e.Roll = math.Atan2(m[1][0], m[1][1]) * deg
+	} else {
+		e.Yaw = math.Atan2(-m[2][0], m[0][0]) * deg
+	}
+	return e
+}
+
+// Matrix builds the rotation Ry(Yaw) * Rx(Pitch) * Rz(Roll).
+func (e EulerAngles) Matrix() Matrix3 {
+	const rad = math.Pi / 180
+	sa, ca := math.Sincos(e.Yaw * rad)
+	sb, cb := math.Sincos(e.Pitch * rad)
+	sc, cc := math.Sincos(e.Roll * rad)
+	return Matrix3{
+		{ca*cc + sa*sb*sc, -ca*sc + sa*sb*cc, sa * cb},
+		{cb * sc, cb * cc, -sb},
+		{-sa*cc + ca*sb*sc, sa*sc + ca*sb*cc, ca * cb},
+	}
+}
+
+// LookAtRotation orients an object at from so its forward (-Z) points at to
+// and its up (+Y) is as close to up as possible, for cameras and turrets. A nil
+// up means +Y. If up is parallel to the view direction, +Z (or +X) stands in.
+// from == to gives the identity.
+func LookAtRotation(from, to, up []float64) Rotation {
+	f := normalize3([3]float64{to[0] - from[0], to[1] - from[1], to[2] - from[2]})
+	if f == ([3]float64{}) {
+		return newRotation(IdentityQuaternion.Matrix())
+	}
+	u := [3]float64{0, 1, 0}
+	if len(up) >= 3 {
+		u = vec3f(up)
+	}
+	for _, alt := range [][3]float64{{0, 0, 1}, {1, 0, 0}} {
+		if tangent(u, f) != ([3]float64{}) {
+			break
+		}
+		u = alt
+	}
+	y := tangent(u, f)
+	z := [3]float64{-f[0], -f[1], -f[2]}
+	x := cross3(y, z)
+	return newRotation(Matrix3{
+		{x[0], y[0], z[0]},
+		{x[1], y[1], z[1]},
+		{x[2], y[2], z[2]},
+	})
+}
+
+// TangentFrame is the local east/north/up basis at a point on a planet, north
+// being toward the +Y pole. Rotation maps local X to East, Y to Up and -Z to
+// North, so an object with it stands on the surface facing north.
+type TangentFrame struct {
+	East, North, Up []float64
+	Rotation
+}
+
+// TangentBasis builds the TangentFrame at position on the sphere around
+// center. At the poles, where north is undefined, north is taken as the
+// projection of world -Z as in SurfaceMatrix.
+func TangentBasis(center, position []float64) TangentFrame {
+	m := SurfaceMatrix(center, position, []float64{0, 1, 0})
+	return TangentFrame{
+		East:     []float64{m[0][0], m[1][0], m[2][0]},
+		North:    []float64{-m[0][2], -m[1][2], -m[2][2]},
+		Up:       []float64{m[0][1], m[1][1], m[2][1]},
+		Rotation: newRotation(m),
+	}
+}
@@ -0,0 +1,303 @@
+package discover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// --------- PARQUET EXPORT ---------
+
+// The writer below produces the subset of Parquet that analytics tools
+// (DuckDB, Spark, pandas) read without options: one row group, REQUIRED flat
+// columns, PLAIN encoding, no compression. Metadata is Thrift compact
+// protocol, hand-encoded to keep the package dependency-free.
+
+// ExportPlanetsParquet writes Planets as a Parquet table sorted by name, with
+// columns name, universe, x, y, z, seed, biome_type, resource_count,
+// tree_count, host and port.
+func (d *Discover) ExportPlanetsParquet(w io.Writer) error {
+	planets := d.PlanetList()
+	n := len(planets)
+	cols := []parquetColumn{
+		{name: "name", strs: make([]string, n)},
+		{name: "universe", strs: make([]string, n)},
+		{name: "x", doubles: make([]float64, n)},
+		{name: "y", doubles: make([]float64, n)},
+		{name: "z", doubles: make([]float64, n)},
+		{name: "seed", ints: make([]int64, n)},
+		{name: "biome_type", ints: make([]int64, n)},
+		{name: "resource_count", ints: make([]int64, n)},
+		{name: "tree_count", ints: make([]int64, n)},
+		{name: "host", strs: make([]string, n)},
+		{name: "port", ints: make([]int64, n)},
+	}
+	for i, p := range planets {
+		cols[0].strs[i], cols[1].strs[i] = p.Name, p.Universe
+		cols[2].doubles[i], cols[3].doubles[i], cols[4].doubles[i] = p.Coordinates[0], p.Coordinates[1], p.Coordinates[2]
+		cols[5].ints[i], cols[6].ints[i] = int64(p.Seed), int64(p.BiomeType)
+		cols[7].ints[i], cols[8].ints[i] = int64(len(p.ResourceLocations)), int64(len(p.TreeLocations))
+		cols[9].strs[i], cols[10].ints[i] = p.Host, int64(p.Port)
+	}
+	return writeParquet(w, n, cols)
+}
+
+// ExportResultsParquet writes Results as a Parquet table, one row per pod
+// scan, with columns host, port, success, error, cubes, planets, warnings,
+// auth_index and dial_seconds.
+func (d *Discover) ExportResultsParquet(w io.Writer) error {
+	results := d.ResultList()
+	n := len(results)
+	cols := []parquetColumn{
+		{name: "host", strs: make([]string, n)},
+		{name: "port", ints: make([]int64, n)},
+		{name: "success", bools: make([]bool, n)},
+		{name: "error", strs: make([]string, n)},
+		{name: "cubes", ints: make([]int64, n)},
+		{name: "planets", ints: make([]int64, n)},
+		{name: "warnings", ints: make([]int64, n)},
+		{name: "auth_index", ints: make([]int64, n)},
+		{name: "dial_seconds", doubles: make([]float64, n)},
+	}
+	for i, r := range results {
+		cols[0].strs[i], cols[1].ints[i] = r.Host, int64(r.Port)
+		cols[2].bools[i], cols[3].strs[i] = r.Success, r.Error
+		cols[4].ints[i], cols[5].ints[i], cols[6].ints[i] = int64(len(r.Cubes)), int64(len(r.Planets)), int64(len(r.Warnings))
+		cols[7].ints[i], cols[8].doubles[i] = int64(r.AuthIndex), r.DialTime.Seconds()
+	}
+	return writeParquet(w, n, cols)
+}
+
+// parquetColumn holds one column's values; exactly one slice is set.
+type parquetColumn struct {
+	name    string
+	strs    []string
+	ints    []int64
+	doubles []float64
+	bools   []bool
+}
+
+// Parquet physical types, and other enum values used below.
+const (
+	pqBoolean   = 0
+	pqInt64     = 2
+	pqDouble    = 5
+	pqByteArray = 6
+
+	pqRequired     = 0
+	pqUTF8         = 0 // ConvertedType
+	pqPlain        = 0 // Encoding
+	pqRLE          = 3
+	pqUncompressed = 0
+	pqDataPage     = 0
+)
+
+func (c *parquetColumn) physical() int32 {
+	switch {
+	case c.strs != nil:
+		return pqByteArray
+	case c.doubles != nil:
+		return pqDouble
+	case c.bools != nil:
+		return pqBoolean
+	}
+	return pqInt64
+}
+
+// plain encodes the column's values with the PLAIN encoding.
+func (c *parquetColumn) plain() []byte {
+	var b []byte
+	switch {
+	case c.strs != nil:
+		for _, s := range c.strs {
+			b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+			b = append(b, s...)
+		}
+	case c.doubles != nil:
+		for _, f := range c.doubles {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+		}
+	case c.bools != nil:
+		b = make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				b[i/8] |= 1 << (i % 8)
+			}
+		}
+	default:
+		for _, v := range c.ints {
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		}
+	}
+	return b
+}
+
+const parquetMagic = "PAR1"
+
+func writeParquet(w io.Writer, rows int, cols []parquetColumn) error {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	type chunk struct{ offset, size int64 }
+	chunks := make([]chunk, len(cols))
+	if rows > 0 {
+		for i := range cols {
+			data := cols[i].plain()
+			var ph thriftWriter
+			ph.i32(1, pqDataPage)
+			ph.i32(2, int32(len(data)))
+			ph.i32(3, int32(len(data)))
+			ph.beginStruct(5) // DataPageHeader
+			ph.i32(1, int32(rows))
+			ph.i32(2, pqPlain)
+			ph.i32(3, pqRLE)
+			ph.i32(4, pqRLE)
+			ph.endStruct()
+			ph.stop()
+
+			chunks[i].offset = int64(file.Len())
+			file.Write(ph.b)
+			file.Write(data)
+			chunks[i].size = int64(file.Len()) - chunks[i].offset
+		}
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1) // version
+	meta.listBegin(2, thriftStruct, len(cols)+1)
+	meta.elemBegin() // root
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(cols)))
+	meta.elemEnd()
+	for i := range cols {
+		meta.elemBegin()
+		meta.i32(1, cols[i].physical())
+		meta.i32(3, pqRequired)
+		meta.binary(4, cols[i].name)
+		if cols[i].strs != nil {
+			meta.i32(6, pqUTF8)
+			meta.beginStruct(10) // LogicalType
+			meta.beginStruct(1)  // STRING
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.elemEnd()
+	}
+	meta.i64(3, int64(rows))
+	groups := 0
+	if rows > 0 {
+		groups = 1
+	}
+	meta.listBegin(4, thriftStruct, groups)
+	if groups == 1 {
+		var total int64
+		for _, c := range chunks {
+			total += c.size
+		}
+		meta.elemBegin()
+		meta.listBegin(1, thriftStruct, len(cols))
+		for i := range cols {
+			meta.elemBegin() // ColumnChunk
+			meta.i64(2, chunks[i].offset)
+			meta.beginStruct(3) // ColumnMetaData
+			meta.i32(1, cols[i].physical())
+			meta.listBegin(2, thriftI32, 1)
+			meta.varint(pqPlain)
+			meta.listBegin(3, thriftBinary, 1)
+			meta.str(cols[i].name)
+			meta.i32(4, pqUncompressed)
+			meta.i64(5, int64(rows))
+			meta.i64(6, chunks[i].size)
+			meta.i64(7, chunks[i].size)
+			meta.i64(9, chunks[i].offset)
+			meta.endStruct()
+			meta.elemEnd()
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(rows))
+		meta.elemEnd()
+	}
+	meta.binary(6, "github.com/OpenFluke/discover")
+	meta.stop()
+
+	file.Write(meta.b)
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.b))))
+	file.WriteString(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// --- thrift compact protocol ---
+
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol. Field ids must
+// be written in increasing order within each struct.
+type thriftWriter struct {
+	b     []byte
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.b = binary.AppendUvarint(t.b, uint64((v<<1)^(v>>63)))
+}
+
+func (t *thriftWriter) str(s string) {
+	t.b = binary.AppendUvarint(t.b, uint64(len(s)))
+	t.b = append(t.b, s...)
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.b = append(t.b, byte(delta)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) { t.field(id, thriftI32); t.varint(int64(v)) }
+func (t *thriftWriter) i64(id int16, v int64) { t.field(id, thriftI64); t.varint(v) }
+
+func (t *thriftWriter) binary(id int16, s string) { t.field(id, thriftBinary); t.str(s) }
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elem)
+		return
+	}
+	t.b = append(t.b, 0xF0|elem)
+	t.b = binary.AppendUvarint(t.b, uint64(n))
+}
+
+// beginStruct starts a struct-valued field; elemBegin starts a struct list
+// element. Both are closed by endStruct/elemEnd.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() { t.elemEnd() }
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() { t.b = append(t.b, 0) }
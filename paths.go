@@ -0,0 +1,106 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+)
+
+// --------- PATHS AND ORBITS ---------
+
+// GreatCirclePath returns steps+1 waypoints along the shortest surface route
+// from a to b on the sphere of the given radius around center, a and b
+// included (both projected onto the sphere). For antipodal points, where every
+// great circle is shortest, the route heads toward +Y first (or -Z if a is on
+// the Y axis). steps < 1 is treated as 1.
+func GreatCirclePath(center []float64, radius float64, a, b []float64, steps int) [][]float64 {
+	if steps < 1 {
+		steps = 1
+	}
+	c := vec3f(center)
+	u := normalize3([3]float64{a[0] - c[0], a[1] - c[1], a[2] - c[2]})
+	v := normalize3([3]float64{b[0] - c[0], b[1] - c[1], b[2] - c[2]})
+	omega := math.Acos(math.Max(-1, math.Min(1, u[0]*v[0]+u[1]*v[1]+u[2]*v[2])))
+
+	// w is the unit tangent at u heading toward v.
+	w := tangent(v, u)
+	if w == ([3]float64{}) {
+		if w = tangent([3]float64{0, 1, 0}, u); w == ([3]float64{}) {
+			w = tangent([3]float64{0, 0, -1}, u)
+		}
+	}
+	points := make([][]float64, steps+1)
+	for i := range points {
+		t := omega * float64(i) / float64(steps)
+		s, co := math.Sincos(t)
+		points[i] = []float64{
+			c[0] + radius*(co*u[0]+s*w[0]),
+			c[1] + radius*(co*u[1]+s*w[1]),
+			c[2] + radius*(co*u[2]+s*w[2]),
+		}
+	}
+	return points
+}
+
+// --- orbits ---
+
+// Orbit describes a closed Keplerian orbit around a planet. Before tilting,
+// the orbit lies in the equatorial (XZ) plane with periapsis on +X, moving
+// toward +Z like LatitudeRing.
+type Orbit struct {
+	Radius       float64 // semi-major axis; the radius of a circular orbit
+	Eccentricity float64 // 0 = circle; clamped below 1
+	Inclination  float64 // degrees the orbital plane is tilted about X
+	Node         float64 // degrees the tilted plane is then turned about Y (longitude of ascending node)
+	Phase        float64 // degrees of mean anomaly at the first waypoint
+}
+
+// OrbitPath returns steps waypoints around center, evenly spaced in time
+// (mean anomaly), so craft following them at a constant rate move fast near
+// periapsis and slow near apoapsis. The loop is closed: the point after the
+// last is the first.
+func OrbitPath(center []float64, o Orbit, steps int) [][]float64 {
+	const rad = math.Pi / 180
+	e := math.Max(0, math.Min(o.Eccentricity, 0.999999))
+	b := o.Radius * math.Sqrt(1-e*e)
+	sinI, cosI := math.Sincos(o.Inclination * rad)
+	sinN, cosN := math.Sincos(o.Node * rad)
+
+	points := make([][]float64, max(steps, 0))
+	for i := range points {
+		m := o.Phase*rad + 2*math.Pi*float64(i)/float64(steps)
+		E := eccentricAnomaly(m, e)
+		x, y, z := o.Radius*(math.Cos(E)-e), 0.0, b*math.Sin(E)
+		y, z = y*cosI-z*sinI, y*sinI+z*cosI
+		x, z = x*cosN+z*sinN, -x*sinN+z*cosN
+		points[i] = []float64{center[0] + x, center[1] + y, center[2] + z}
+	}
+	return points
+}
+
+// eccentricAnomaly solves Kepler's equation M = E - e*sin(E) for E.
+func eccentricAnomaly(m, e float64) float64 {
+	E := m
+	if e > 0.8 {
+		E = math.Pi // better start for very eccentric orbits
+	}
+	for i := 0; i < 50; i++ {
+		d := (E - e*math.Sin(E) - m) / (1 - e*math.Cos(E))
+		E -= d
+		if math.Abs(d) < 1e-12 {
+			break
+		}
+	}
+	return E
+}
+
+// GenerateOrbit is OrbitPath around a planet, cached like
+// GenerateSpawnPositions. Use it for patrol routes and satellites.
+func (d *Discover) GenerateOrbit(planetName string, o Orbit, steps int) ([][]float64, error) {
+	v, err := d.Derived(planetName, fmt.Sprintf("orbit/%g/%g/%g/%g/%g/%d", o.Radius, o.Eccentricity, o.Inclination, o.Node, o.Phase, steps), func(planet PlanetRecord) (any, error) {
+		return OrbitPath(planet.Coordinates[:], o, steps), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPoints(v.([][]float64)), nil
+}
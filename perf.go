@@ -0,0 +1,90 @@
+package discover
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --------- PERFORMANCE COUNTERS ---------
+
+// PerfStat aggregates timings for one instrumented operation.
+type PerfStat struct {
+	Calls int64
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean is the average duration per call.
+func (s PerfStat) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+var (
+	perfEnabled atomic.Bool
+	perfMu      sync.Mutex
+	perfStats   = map[string]PerfStat{}
+)
+
+// EnablePerfCounters starts timing hot operations (sphere generation,
+// nearest-planet and spawn checks, message framing). Counters are off by
+// default and cost a single atomic load per call while disabled.
+func EnablePerfCounters() { perfEnabled.Store(true) }
+
+// DisablePerfCounters stops timing; collected stats are kept.
+func DisablePerfCounters() { perfEnabled.Store(false) }
+
+// ResetPerfCounters clears collected stats.
+func ResetPerfCounters() {
+	perfMu.Lock()
+	perfStats = map[string]PerfStat{}
+	perfMu.Unlock()
+}
+
+// PerfCounters returns a copy of the collected stats keyed by operation name.
+func PerfCounters() map[string]PerfStat {
+	perfMu.Lock()
+	defer perfMu.Unlock()
+	out := make(map[string]PerfStat, len(perfStats))
+	for k, v := range perfStats {
+		out[k] = v
+	}
+	return out
+}
+
+func noopPerf() {}
+
+// perfTrack starts timing op; call the returned func when it finishes:
+//
+//	defer perfTrack("FindClosestPlanet")()
+//
+// It is small enough to inline, so while counters are off a call site pays
+// one atomic load and an empty deferred call: no closure, no clock reads.
+func perfTrack(op string) func() {
+	if !perfEnabled.Load() {
+		return noopPerf
+	}
+	return perfStart(op)
+}
+
+// perfStart is kept out of line so perfTrack stays within the inlining budget.
+//
+//go:noinline
+func perfStart(op string) func() {
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		perfMu.Lock()
+		s := perfStats[op]
+		s.Calls++
+		s.Total += d
+		if d > s.Max {
+			s.Max = d
+		}
+		perfStats[op] = s
+		perfMu.Unlock()
+	}
+}
@@ -0,0 +1,141 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Benchmarks for the hot paths, over a synthetic 10k-planet galaxy:
+//
+//	go test -run '^$' -bench .
+//
+// TestPerfBudget runs the same benchmarks against perfBudget when asked to
+// (go test -run PerfBudget -perfbudget), to catch order-of-magnitude
+// regressions in CI.
+
+var perfBudgetFlag = flag.Bool("perfbudget", false, "check benchmarks against perfBudget")
+
+// perfBudget holds deliberately loose limits that a regression of an order of
+// magnitude will still trip on ordinary hardware.
+var perfBudget = map[string]time.Duration{
+	"FibonacciSphere/1000":           1 * time.Millisecond,
+	"FindClosestPlanet/10000":        2 * time.Millisecond,
+	"IsSpawnPointFree/10000":         2 * time.Millisecond,
+	"ReadDelimited/64KiB":            2 * time.Millisecond,
+	"ReadLengthPrefixed/64KiB":       1 * time.Millisecond,
+	"GenerateSpawnPositions/100@10k": 1 * time.Millisecond,
+}
+
+var (
+	benchPoint = []float64{12345, -2345, 777}
+	benchMsg   = strings.Repeat("x", 64<<10)
+	benchDelim = "<???DONE???---"
+)
+
+var perfBenches = map[string]func(b *testing.B){
+	"FibonacciSphere/1000": func(b *testing.B) {
+		center := []float64{0, 0, 0}
+		for i := 0; i < b.N; i++ {
+			FibonacciSphere(1000, 100, center)
+		}
+	},
+	"FindClosestPlanet/10000": func(b *testing.B) {
+		d := syntheticGalaxy(10000)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d.FindClosestPlanet(benchPoint)
+		}
+	},
+	"IsSpawnPointFree/10000": func(b *testing.B) {
+		d := syntheticGalaxy(10000)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d.IsSpawnPointFree(benchPoint, 1)
+		}
+	},
+	"GenerateSpawnPositions/100@10k": func(b *testing.B) {
+		d := syntheticGalaxy(10000)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d.GenerateSpawnPositions("p0", 100, 50)
+		}
+	},
+	"ReadDelimited/64KiB": func(b *testing.B) {
+		wire := []byte(benchMsg + benchDelim)
+		for i := 0; i < b.N; i++ {
+			readDelimited(bufio.NewReader(bytes.NewReader(wire)), benchDelim)
+		}
+	},
+	"ReadLengthPrefixed/64KiB": func(b *testing.B) {
+		var wire bytes.Buffer
+		(&podConn{conn: nopConn{&wire}, framing: FramingLengthPrefix}).sendMsg(benchMsg)
+		frame := wire.Bytes()
+		for i := 0; i < b.N; i++ {
+			pc := &podConn{conn: nopConn{}, reader: bufio.NewReader(bytes.NewReader(frame)), framing: FramingLengthPrefix}
+			pc.readMsg()
+		}
+	},
+}
+
+func BenchmarkHotPaths(b *testing.B) {
+	for name, fn := range perfBenches {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			fn(b)
+		})
+	}
+}
+
+func TestPerfBudget(t *testing.T) {
+	if !*perfBudgetFlag {
+		t.Skip("pass -perfbudget to check benchmark budgets")
+	}
+	for name, fn := range perfBenches {
+		r := testing.Benchmark(fn)
+		if got := time.Duration(r.NsPerOp()); got > perfBudget[name] {
+			t.Errorf("%s: %v per op, budget %v", name, got, perfBudget[name])
+		}
+	}
+}
+
+// syntheticGalaxy builds a Discover with n planets on a cubic grid.
+func syntheticGalaxy(n int) *Discover {
+	d := NewDiscover(Config{})
+	side := 1
+	for side*side*side < n {
+		side++
+	}
+	for i := 0; i < n; i++ {
+		x, y, z := i%side, (i/side)%side, i/(side*side)
+		name := fmt.Sprintf("p%d", i)
+		d.Planets[name] = PlanetRecord{Name: name, Coordinates: Vec3{float64(x) * 800, float64(y) * 800, float64(z) * 800}}
+	}
+	return d
+}
+
+// nopConn is a net.Conn that writes to w and ignores deadlines, for
+// benchmarking framing without sockets.
+type nopConn struct {
+	w io.Writer
+}
+
+func (c nopConn) Read([]byte) (int, error) { return 0, io.EOF }
+func (c nopConn) Write(p []byte) (int, error) {
+	if c.w == nil {
+		return len(p), nil
+	}
+	return c.w.Write(p)
+}
+func (nopConn) Close() error                     { return nil }
+func (nopConn) LocalAddr() net.Addr              { return nil }
+func (nopConn) RemoteAddr() net.Addr             { return nil }
+func (nopConn) SetDeadline(time.Time) error      { return nil }
+func (nopConn) SetReadDeadline(time.Time) error  { return nil }
+func (nopConn) SetWriteDeadline(time.Time) error { return nil }
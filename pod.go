@@ -3,30 +3,62 @@ package discover
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 // --- Result Types ---
 
 type PlanetRecord struct {
-	Name        string
-	Coordinates [3]float64
-	Host        string
-	Port        int
+	Name              string
+	Universe          string // key of the get_planets reply the planet came from
+	Coordinates       Vec3
+	Seed              int
+	BiomeType         int
+	ResourceLocations [][3]float64
+	TreeLocations     [][3]float64
+	PodRef            // pod that reported the planet
 }
 
 type PodResult struct {
-	Host    string
-	Port    int
+	PodRef
 	Success bool
 	Error   string
 	Cubes   []string
 	Planets []PlanetRecord
+	Extras  map[string]string // raw replies to extra scan steps, keyed by step type
+	// AuthIndex is the Config.AuthPasses entry the pod accepted (0 without AuthPasses).
+	AuthIndex int
+	Warnings  []Warning                  // non-fatal problems noticed during the scan
+	CubeState map[string]json.RawMessage // per-cube objects, when the pod lists cubes that way
+	DialTime  time.Duration              // time to connect and authenticate, including failed attempts
+	// Session is the ScanAll/RescanPods run that produced the result (see
+	// Sessions) and ScannedAt when the pod's scan finished; both are empty
+	// for direct ScanPodConfig calls.
+	Session   string
+	ScannedAt time.Time
+}
+
+// PlanetRecord and PodResult embed PodRef; these keep fmt from printing only
+// the promoted PodRef.String.
+
+func (p PlanetRecord) String() string {
+	return fmt.Sprintf("%s %v on %s", p.Name, p.Coordinates, p.PodRef)
+}
+
+func (r PodResult) String() string {
+	if !r.Success {
+		return fmt.Sprintf("%s failed: %s", r.PodRef, r.Error)
+	}
+	return fmt.Sprintf("%s ok cubes=%d planets=%d", r.PodRef, len(r.Cubes), len(r.Planets))
 }
 
 // --- Full planet struct for server JSON ---
@@ -38,63 +70,472 @@ type Planet struct {
 	ResourceLocations []map[string]float64 `json:"ResourceLocations"`
 	TreeLocations     []map[string]float64 `json:"TreeLocations"`
 	BiomeType         int                  `json:"BiomeType"`
+	unknown           []string             // JSON fields not listed above, see UnmarshalJSON
 }
 
 // --- Main scan logic ---
 
+// ScanPod scans a single pod using delimiter framing. Use ScanPodConfig to
+// pick other options such as length-prefixed framing.
 func ScanPod(host string, port int, auth string, delim string, timeout int) PodResult {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", addr, time.Duration(timeout)*time.Second)
+	return ScanPodConfig(Config{AuthPass: auth, Delimiter: delim, TimeoutSec: timeout}, PodRef{Host: host, Port: port})
+}
+
+// ScanPodConfig scans a single pod using the auth, framing, timeout and scan
+// step settings from cfg.
+func ScanPodConfig(cfg Config, pod PodRef) PodResult {
+	dialStart := time.Now()
+	pc, err := dialPod(cfg, pod)
+	dialTime := time.Since(dialStart)
 	if err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: err.Error()}
+		return PodResult{PodRef: pod, Success: false, Error: err.Error(), DialTime: dialTime}
+	}
+	defer func() { pc.Close() }()
+
+	result := PodResult{PodRef: pod, DialTime: dialTime}
+	if len(cfg.AuthPasses) > 0 {
+		result.AuthIndex, _ = AuthCredential(cfg, pod)
+	}
+	var calls []*scanCall
+	for _, step := range cfg.scanSteps() {
+		calls = append(calls, scanCalls(cfg, step, &result)...)
+	}
+	for redials := 0; ; redials++ {
+		n, errMsg, dropped := runScanCalls(pc, calls, cfg.Pipeline)
+		if errMsg == "" {
+			break
+		}
+		if !dropped || redials >= scanRedials {
+			return PodResult{PodRef: pod, Success: false, Error: errMsg, DialTime: dialTime}
+		}
+		// The connection dropped mid-scan: redial and resume with the
+		// requests that haven't been answered yet.
+		result.Warnings = append(result.Warnings, Warning{
+			Pod: pod, Kind: WarnTruncated, Command: calls[n].cmdType,
+			Message: "connection dropped (" + errMsg + "); redialed and resumed",
+		})
+		pc.Close()
+		fresh, err := dialPod(cfg, pod)
+		if err != nil {
+			return PodResult{PodRef: pod, Success: false, Error: errMsg, DialTime: dialTime}
+		}
+		pc = fresh
+		calls = calls[n:]
+	}
+	result.Success = true
+	return result
+}
+
+// scanRedials is how many times a scan reconnects after the pod drops the
+// connection between steps.
+const scanRedials = 1
+
+// --- Scan steps ---
+
+// Standard scan step types.
+const (
+	StepCubes   = "get_cube_list"
+	StepPlanets = "get_planets"
+)
+
+// ScanStep is one request sent to every pod during a scan. The standard steps
+// (StepCubes, StepPlanets) fill PodResult.Cubes and PodResult.Planets. Any
+// other Type is an extra request: Payload is sent (or {"type":Type} when nil)
+// and the raw reply is stored in PodResult.Extras[Type].
+type ScanStep struct {
+	Type    string
+	Payload any
+}
+
+// DefaultScanSteps is used when Config.Steps is nil.
+var DefaultScanSteps = []ScanStep{{Type: StepCubes}, {Type: StepPlanets}}
+
+// scanSteps returns the configured steps. A nil slice means the defaults; an
+// empty, non-nil slice only authenticates, which makes a cheap health sweep.
+func (cfg Config) scanSteps() []ScanStep {
+	if cfg.Steps == nil {
+		return DefaultScanSteps
+	}
+	return cfg.Steps
+}
+
+// scanCall is one request/reply exchange of a scan step: a typed command
+// (req/resp), or for extra steps a raw payload whose reply is kept verbatim.
+type scanCall struct {
+	cmdType            string
+	req, resp          any
+	payload            any
+	reqFail, parseFail string
+	done               func(raw string) // stores the decoded reply in the result
+}
+
+// scanCalls expands a step into its exchanges.
+func scanCalls(cfg Config, step ScanStep, result *PodResult) []*scanCall {
+	switch step.Type {
+	case StepCubes:
+		cubes := &CubeListResponse{}
+		return []*scanCall{{
+			cmdType: StepCubes, req: CubeListRequest{}, resp: cubes,
+			reqFail: "Cube req fail", parseFail: "Cube parse fail",
+			done: func(string) { result.Cubes, result.CubeState = cubes.Cubes, cubes.State },
+		}}
+
+	case StepPlanets:
+		universes := cfg.Universes
+		if len(universes) == 0 {
+			universes = []string{""} // one request, every universe the pod reports
+		}
+		var calls []*scanCall
+		for _, u := range universes {
+			planets := &PlanetsResponse{}
+			calls = append(calls, &scanCall{
+				cmdType: StepPlanets, req: PlanetsRequest{Universe: u}, resp: planets,
+				reqFail: "Planet req fail", parseFail: "Planet parse fail",
+				done: func(string) {
+					for universe, ps := range *planets {
+						kept := ps[:0]
+						for _, p := range ps {
+							result.Warnings = append(result.Warnings, planetWarnings(p, result.PodRef)...)
+							if sanitizePlanet(cfg.Coordinates, &p, result.PodRef, &result.Warnings) {
+								kept = append(kept, p)
+							}
+						}
+						(*planets)[universe] = kept
+					}
+					result.Planets = append(result.Planets, planetRecords(*planets, u, result.PodRef)...)
+				},
+			})
+		}
+		return calls
+
+	default:
+		payload := step.Payload
+		if payload == nil {
+			payload = map[string]string{"type": step.Type}
+		}
+		return []*scanCall{{
+			cmdType: step.Type, payload: payload,
+			reqFail: step.Type + " req fail", parseFail: step.Type + " read fail",
+			done: func(raw string) {
+				if result.Extras == nil {
+					result.Extras = make(map[string]string)
+				}
+				result.Extras[step.Type] = raw
+			},
+		}}
 	}
-	defer conn.Close()
+}
 
-	// Authenticate
-	if err := sendMsg(conn, auth, delim); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Auth failed"}
+// send and read return a PodResult error message on failure, and whether the
+// failure was the connection dropping (worth a redial).
+func (c *scanCall) send(pc msgTransport) (string, bool) {
+	if c.req == nil {
+		msg, err := encodeCommand(c.payload)
+		if err != nil {
+			return c.cmdType + " encode fail", false
+		}
+		if err := pc.sendMsg(msg); err != nil {
+			return c.reqFail, connDropped(err)
+		}
+		return "", false
 	}
-	if !strings.Contains(readMsg(conn, timeout, delim), "auth_success") {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Bad password"}
+	if err := sendTypedRequest(pc, c.cmdType, c.req, c.resp); err != nil {
+		return stepError(err, c.reqFail, c.parseFail), connDropped(err)
 	}
+	return "", false
+}
 
-	// Get Cubes
-	if err := sendMsg(conn, `{"type":"get_cube_list"}`, delim); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Cube req fail"}
+func (c *scanCall) read(pc msgTransport) (string, bool) {
+	if c.req == nil {
+		raw, err := pc.readMsg()
+		if err != nil {
+			return c.parseFail, connDropped(err)
+		}
+		c.done(raw)
+		return "", false
 	}
-	var cubesData map[string]interface{}
-	if err := json.Unmarshal([]byte(readMsg(conn, timeout, delim)), &cubesData); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Cube parse fail"}
+	if err := readTypedReply(pc, c.cmdType, c.resp); err != nil {
+		return stepError(err, c.reqFail, c.parseFail), connDropped(err)
 	}
-	cubes := toStringSlice(cubesData["cubes"])
+	c.done("")
+	return "", false
+}
 
-	// Get Planets (server returns: map[string][]Planet)
-	if err := sendMsg(conn, `{"type":"get_planets"}`, delim); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Planet req fail"}
+// runScanCalls performs the exchanges in order. On failure it returns how
+// many calls completed, a PodResult error message, and whether the connection
+// dropped. With pipeline set, every request is written before the first reply
+// is read, so a scan costs one round trip instead of one per request; pods
+// must answer in request order.
+func runScanCalls(pc msgTransport, calls []*scanCall, pipeline bool) (int, string, bool) {
+	if pipeline {
+		for _, c := range calls {
+			if errMsg, dropped := c.send(pc); errMsg != "" {
+				return 0, errMsg, dropped
+			}
+		}
+		for i, c := range calls {
+			if errMsg, dropped := c.read(pc); errMsg != "" {
+				return i, errMsg, dropped
+			}
+		}
+		return len(calls), "", false
 	}
-	raw := readMsg(conn, timeout, delim)
-	var planetsData map[string][]Planet
-	if err := json.Unmarshal([]byte(raw), &planetsData); err != nil {
-		return PodResult{Host: host, Port: port, Success: false, Error: "Planet parse fail"}
+	for i, c := range calls {
+		if errMsg, dropped := c.send(pc); errMsg != "" {
+			return i, errMsg, dropped
+		}
+		if errMsg, dropped := c.read(pc); errMsg != "" {
+			return i, errMsg, dropped
+		}
 	}
-	var planetRecords []PlanetRecord
-	for _, ps := range planetsData {
+	return len(calls), "", false
+}
+
+// connDropped reports whether err means the peer closed or reset the
+// connection, as opposed to a timeout or a bad reply.
+func connDropped(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return false
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// stepError maps a typed call failure to the short PodResult error messages.
+// Read errors count as parse failures, as they always have in PodResult.Error.
+func stepError(err error, reqFail, parseFail string) string {
+	var cerr *CommandError
+	if errors.As(err, &cerr) && cerr.Stage == "send" {
+		return reqFail
+	}
+	return parseFail
+}
+
+// planetRecords flattens a get_planets reply. The reply's keys name the
+// universes; requested is used for replies keyed by an empty string.
+func planetRecords(planetsData PlanetsResponse, requested string, pod PodRef) []PlanetRecord {
+	var records []PlanetRecord
+	for universe, ps := range planetsData {
+		if universe == "" {
+			universe = requested
+		}
 		for _, p := range ps {
-			coords := [3]float64{0, 0, 0}
-			if p.Position != nil {
-				coords[0] = p.Position["x"]
-				coords[1] = p.Position["y"]
-				coords[2] = p.Position["z"]
-			}
-			planetRecords = append(planetRecords, PlanetRecord{
-				Name:        p.Name,
-				Coordinates: coords,
-				Host:        host,
-				Port:        port,
+			records = append(records, PlanetRecord{
+				Name:              p.Name,
+				Universe:          universe,
+				Coordinates:       vec3(p.Position),
+				Seed:              p.Seed,
+				BiomeType:         p.BiomeType,
+				ResourceLocations: vec3s(p.ResourceLocations),
+				TreeLocations:     vec3s(p.TreeLocations),
+				PodRef:            pod,
 			})
 		}
 	}
-	return PodResult{Host: host, Port: port, Success: true, Cubes: cubes, Planets: planetRecords}
+	return records
+}
+
+// vec3 reads an {"x","y","z"} map; missing axes are 0.
+func vec3(m map[string]float64) [3]float64 {
+	return [3]float64{m["x"], m["y"], m["z"]}
+}
+
+func vec3s(ms []map[string]float64) [][3]float64 {
+	if len(ms) == 0 {
+		return nil
+	}
+	out := make([][3]float64, len(ms))
+	for i, m := range ms {
+		out[i] = vec3(m)
+	}
+	return out
+}
+
+// --- Connection setup ---
+
+var (
+	errAuthFailed  = errors.New("Auth failed")
+	errBadPassword = errors.New("Bad password")
+	errNotPod      = errors.New("Not a pod")
+)
+
+// bannerWindow is how long the pre-probe listens for an unsolicited banner.
+const bannerWindow = 50 * time.Millisecond
+
+// dialPod connects to a pod and authenticates. The returned errors carry the
+// same messages ScanPod has always reported in PodResult.Error.
+//
+// With Config.AuthPasses, each password is tried in order (starting with the
+// one that last worked for this pod) until one isn't rejected.
+func dialPod(cfg Config, pod PodRef) (msgTransport, error) {
+	if len(cfg.AuthPasses) == 0 {
+		return dialAuth(cfg, pod)
+	}
+	order := make([]int, 0, len(cfg.AuthPasses))
+	if i, ok := AuthCredential(cfg, pod); ok && i < len(cfg.AuthPasses) {
+		order = append(order, i)
+	}
+	for i := range cfg.AuthPasses {
+		if len(order) == 0 || i != order[0] {
+			order = append(order, i)
+		}
+	}
+	for _, i := range order {
+		c := cfg
+		c.AuthPass = cfg.AuthPasses[i]
+		pc, err := dialAuth(c, pod)
+		if err == errBadPassword {
+			continue
+		}
+		if err == nil {
+			authCredentials.Store(authKey(cfg, pod), i)
+		}
+		return pc, err
+	}
+	return nil, errBadPassword
+}
+
+// authCredentials remembers which AuthPasses entry each pod accepted, per
+// AuthPasses list, so scanners with different passwords for the same pod
+// don't overwrite each other.
+var authCredentials sync.Map // podMemoKey -> int
+
+// AuthCredential returns the index of the cfg.AuthPasses entry pod last
+// accepted, if any.
+func AuthCredential(cfg Config, pod PodRef) (int, bool) {
+	v, ok := authCredentials.Load(authKey(cfg, pod))
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func authKey(cfg Config, pod PodRef) podMemoKey {
+	return memoKey(pod, cfg.AuthPasses...)
+}
+
+// podMemoKey keys per-pod facts learned while dialing by the settings that
+// produced them (hashed, so passwords aren't kept as map keys).
+type podMemoKey struct {
+	pod      PodRef
+	settings [sha256.Size]byte
+}
+
+func memoKey(pod PodRef, settings ...string) podMemoKey {
+	h := sha256.New()
+	for _, s := range settings {
+		fmt.Fprintf(h, "%d:%s,", len(s), s)
+	}
+	k := podMemoKey{pod: pod}
+	h.Sum(k.settings[:0])
+	return k
+}
+
+// dialAuth connects and authenticates with cfg.AuthPass.
+func dialAuth(cfg Config, pod PodRef) (msgTransport, error) {
+	if cfg.AutoDelimiter && cfg.Framing == FramingDelimiter && !isWebSocketURL(pod.Host) {
+		pc, resp, err := detectAndAuth(cfg, pod)
+		if err != nil {
+			return nil, err
+		}
+		return finishAuth(cfg, pc, resp)
+	}
+	pc, err := dialTransport(cfg, pod)
+	if err != nil {
+		return nil, err
+	}
+	probing := cfg.PreProbeTimeout > 0
+	if probing {
+		// Fast pre-probe: services that greet first (SSH, SMTP, ...) aren't
+		// pods, and the auth reply must arrive within PreProbeTimeout.
+		if tc, ok := pc.(*podConn); ok && tc.hasBanner(min(bannerWindow, cfg.PreProbeTimeout)) {
+			pc.Close()
+			return nil, errNotPod
+		}
+		pc.setTimeout(cfg.PreProbeTimeout)
+	}
+	if err := pc.sendMsg(cfg.AuthPass); err != nil {
+		pc.Close()
+		return nil, errAuthFailed
+	}
+	resp, err := pc.readMsg()
+	if probing {
+		if err != nil || !looksLikePodReply(resp) {
+			pc.Close()
+			return nil, errNotPod
+		}
+		pc.setTimeout(cfg.readTimeout())
+	}
+	return finishAuth(cfg, pc, resp)
+}
+
+// finishAuth checks the auth reply and runs the post-auth negotiation.
+func finishAuth(cfg Config, pc msgTransport, resp string) (msgTransport, error) {
+	if !strings.Contains(resp, "auth_success") {
+		pc.Close()
+		return nil, errBadPassword
+	}
+	if cfg.Compression != "" {
+		cpc, err := negotiateCompression(pc, cfg.Compression)
+		if err != nil {
+			pc.Close()
+			return nil, err
+		}
+		pc = cpc
+	}
+	if cfg.Protocol == ProtocolProtobuf {
+		ppc, err := negotiateProtocol(pc)
+		if err != nil {
+			pc.Close()
+			return nil, err
+		}
+		pc = ppc
+	}
+	return pc, nil
+}
+
+// looksLikePodReply accepts JSON or anything mentioning auth, which covers
+// both success and rejection messages from real pods.
+func looksLikePodReply(resp string) bool {
+	return json.Valid([]byte(resp)) || strings.Contains(resp, "auth")
+}
+
+// encodeCommand turns a command payload into its wire form. Strings and raw
+// JSON are sent as-is, anything else is marshalled to JSON.
+func encodeCommand(payload any) (string, error) {
+	switch v := payload.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case json.RawMessage:
+		return string(v), nil
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// sendPodCommand opens a fresh authenticated connection, sends one command
+// and returns the pod's reply.
+func sendPodCommand(cfg Config, pod PodRef, payload any) (string, error) {
+	msg, err := encodeCommand(payload)
+	if err != nil {
+		return "", err
+	}
+	pc, err := dialPod(cfg, pod)
+	if err != nil {
+		return "", err
+	}
+	defer pc.Close()
+	if err := pc.sendMsg(msg); err != nil {
+		return "", err
+	}
+	return pc.readMsg()
 }
 
 // --- helpers ---
@@ -106,36 +547,33 @@ func sendMsg(conn net.Conn, msg string, delim string) error {
 
 func readMsg(conn net.Conn, timeout int, delim string) string {
 	conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
-	reader := bufio.NewReader(conn)
-	var buf bytes.Buffer
+	msg, _ := readDelimited(bufio.NewReader(conn), delim)
+	return msg
+}
 
+// readDelimited reads until the buffer ends with delim. Only the tail of the
+// buffer is checked, so the cost doesn't grow with the message size.
+func readDelimited(reader *bufio.Reader, delim string) (string, error) {
+	if delim == "" {
+		return "", errors.New("empty delimiter")
+	}
+	var buf bytes.Buffer
+	last := delim[len(delim)-1]
 	for {
-		chunk, err := reader.ReadString(delim[len(delim)-1]) // read up to possible delim ending char
-		if err != nil && err != io.EOF {
-			break
-		}
-		buf.WriteString(chunk)
-		if strings.Contains(buf.String(), delim) {
+		chunk, err := reader.ReadSlice(last) // read up to possible delim ending char
+		buf.Write(chunk)
+		if bytes.HasSuffix(buf.Bytes(), []byte(delim)) {
 			break
 		}
-		if err == io.EOF {
-			break
+		if err == bufio.ErrBufferFull {
+			continue
 		}
-	}
-	full := buf.String()
-	// Remove delimiter and any trailing/leading whitespace
-	return strings.TrimSpace(strings.ReplaceAll(full, delim, ""))
-}
-
-func toStringSlice(v interface{}) []string {
-	if arr, ok := v.([]interface{}); ok {
-		out := make([]string, 0, len(arr))
-		for _, el := range arr {
-			if str, ok := el.(string); ok {
-				out = append(out, str)
-			}
+		if err != nil {
+			// Return whatever arrived; callers treat a partial message as a parse failure.
+			return strings.TrimSpace(buf.String()), err
 		}
-		return out
 	}
-	return nil
+	full := buf.Bytes()[:buf.Len()-len(delim)]
+	// Remove trailing/leading whitespace
+	return strings.TrimSpace(string(full)), nil
 }
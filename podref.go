@@ -0,0 +1,71 @@
+package discover
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PodRef addresses one pod. For URL targets (ws://, wss://, unix://) Host
+// holds the full URL and Port the port it connects to (0 for Unix sockets).
+type PodRef struct {
+	Host string
+	Port int
+}
+
+// String formats the ref as host:port (bracketing IPv6 hosts), or as the URL
+// for URL targets. ParsePodRef reverses it.
+func (r PodRef) String() string {
+	if isURLTarget(r.Host) {
+		return r.Host
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+}
+
+// ParsePodRef parses "host:port", "[::1]:port" or a ws://, wss:// or unix:// URL.
+func ParsePodRef(s string) (PodRef, error) {
+	if isUnixURL(s) {
+		if unixSocketPath(s) == "" {
+			return PodRef{}, fmt.Errorf("invalid unix socket target %q", s)
+		}
+		return PodRef{Host: s}, nil
+	}
+	if isWebSocketURL(s) {
+		port := webSocketPort(s)
+		if port == 0 {
+			return PodRef{}, fmt.Errorf("invalid pod URL %q", s)
+		}
+		return PodRef{Host: s, Port: port}, nil
+	}
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return PodRef{}, fmt.Errorf("invalid pod address %q: %w", s, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return PodRef{}, fmt.Errorf("invalid pod port in %q", s)
+	}
+	return PodRef{Host: host, Port: port}, nil
+}
+
+// isURLTarget reports whether a host entry is a URL naming a single pod.
+func isURLTarget(host string) bool {
+	return isWebSocketURL(host) || isUnixURL(host)
+}
+
+func urlTargetPort(host string) int {
+	if isWebSocketURL(host) {
+		return webSocketPort(host)
+	}
+	return 0
+}
+
+func isUnixURL(host string) bool {
+	return strings.HasPrefix(host, "unix://")
+}
+
+// unixSocketPath extracts the socket path from unix:///var/run/pod0.sock.
+func unixSocketPath(host string) string {
+	return strings.TrimPrefix(host, "unix://")
+}
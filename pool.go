@@ -0,0 +1,182 @@
+package discover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// --- Client pooling ---
+
+// PoolConfig limits how many connections a ClientPool keeps open.
+type PoolConfig struct {
+	MaxConnsPerHost int           // open connections per host across all its ports; 0 = unlimited
+	IdleTimeout     time.Duration // close connections unused for this long; 0 = never
+}
+
+// ClientPool hands out one PodClient per pod and caps the number of open
+// sockets per physical host. When a host is at its limit, dialing a new pod
+// first closes the least recently used idle connection on that host and
+// otherwise waits for one to be released.
+type ClientPool struct {
+	cfg  Config
+	opts PoolConfig
+
+	mu      sync.Mutex
+	clients map[PodRef]*PodClient
+	slots   map[string]chan struct{} // host -> semaphore of open connections
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewClientPool creates a pool using cfg for every client it creates.
+func NewClientPool(cfg Config, opts PoolConfig) *ClientPool {
+	p := &ClientPool{
+		cfg:     cfg,
+		opts:    opts,
+		clients: make(map[PodRef]*PodClient),
+		slots:   make(map[string]chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if opts.IdleTimeout > 0 {
+		go p.reapLoop()
+	}
+	return p
+}
+
+// Client returns the pooled client for pod, creating it on first use.
+func (p *ClientPool) Client(pod PodRef) *PodClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[pod]; ok {
+		return c
+	}
+	c := NewPodClient(p.cfg, pod)
+	c.pool = p
+	p.clients[pod] = c
+	return c
+}
+
+// Close stops the reaper and closes every pooled connection.
+func (p *ClientPool) Close() {
+	p.once.Do(func() { close(p.done) })
+	p.mu.Lock()
+	clients := make([]*PodClient, 0, len(p.clients))
+	for _, c := range p.clients {
+		clients = append(clients, c)
+	}
+	p.mu.Unlock()
+	for _, c := range clients {
+		c.Close()
+	}
+}
+
+func (p *ClientPool) hostSlots(host string) chan struct{} {
+	if p.opts.MaxConnsPerHost <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sem, ok := p.slots[host]
+	if !ok {
+		sem = make(chan struct{}, p.opts.MaxConnsPerHost)
+		p.slots[host] = sem
+	}
+	return sem
+}
+
+// acquire reserves a connection slot for c's host before it dials.
+func (p *ClientPool) acquire(ctx context.Context, c *PodClient) error {
+	sem := p.hostSlots(c.Host)
+	if sem == nil {
+		return nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+	p.evictIdle(c)
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot held by a connection on host.
+func (p *ClientPool) release(host string) {
+	if sem := p.hostSlots(host); sem != nil {
+		select {
+		case <-sem:
+		default:
+		}
+	}
+}
+
+// evictIdle closes the least recently used idle connection on the same host
+// as c. Busy clients are skipped.
+func (p *ClientPool) evictIdle(c *PodClient) {
+	p.mu.Lock()
+	var victim *PodClient
+	for _, other := range p.clients {
+		if other == c || other.Host != c.Host || !other.isIdle() {
+			continue
+		}
+		if victim == nil || other.idleSince().Before(victim.idleSince()) {
+			victim = other
+		}
+	}
+	p.mu.Unlock()
+	if victim != nil {
+		victim.closeIfIdle(0)
+	}
+}
+
+// minReapInterval bounds how often the reaper wakes for tiny IdleTimeouts.
+const minReapInterval = 10 * time.Millisecond
+
+func (p *ClientPool) reapLoop() {
+	ticker := time.NewTicker(max(p.opts.IdleTimeout/2, minReapInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			clients := make([]*PodClient, 0, len(p.clients))
+			for _, c := range p.clients {
+				clients = append(clients, c)
+			}
+			p.mu.Unlock()
+			for _, c := range clients {
+				c.closeIfIdle(p.opts.IdleTimeout)
+			}
+		}
+	}
+}
+
+// Client returns a pooled PodClient for pod, using Config.Pool limits.
+// The pool is created on first use and shared by all Discover-level commands.
+func (d *Discover) Client(pod PodRef) *PodClient {
+	d.mu.Lock()
+	if d.pool == nil {
+		d.pool = NewClientPool(d.Config, d.Config.Pool)
+	}
+	pool := d.pool
+	d.mu.Unlock()
+	return pool.Client(pod)
+}
+
+// CloseClients closes every pooled client connection.
+func (d *Discover) CloseClients() {
+	d.mu.Lock()
+	pool := d.pool
+	d.pool = nil
+	d.mu.Unlock()
+	if pool != nil {
+		pool.Close()
+	}
+}
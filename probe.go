@@ -0,0 +1,89 @@
+package discover
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- UDP LAN probe ---
+
+// ProbeConfig enables discovery of pods on the local segment. A probe datagram
+// is sent to Addr (a broadcast address such as "255.255.255.255:14999" or a
+// multicast group such as "239.255.20.1:14999") and every pod that answers is
+// added to the scan. Pods reply with their TCP port, either as a bare number
+// or as JSON like {"port":14000}.
+type ProbeConfig struct {
+	Addr    string
+	Message string        // probe payload; defaults to {"type":"discover_probe"}
+	Wait    time.Duration // how long to collect replies; defaults to 2s
+}
+
+const defaultProbeMessage = `{"type":"discover_probe"}`
+
+// ProbeUDP sends a single probe and collects replies until the wait expires.
+// Duplicate replies from the same pod are dropped.
+func ProbeUDP(pc ProbeConfig) ([]PodRef, error) {
+	dst, err := net.ResolveUDPAddr("udp4", pc.Addr)
+	if err != nil {
+		return nil, err
+	}
+	msg := pc.Message
+	if msg == "" {
+		msg = defaultProbeMessage
+	}
+	wait := pc.Wait
+	if wait <= 0 {
+		wait = 2 * time.Second
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.WriteToUDP([]byte(msg), dst); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(wait))
+	var replies []PodRef
+	seen := map[PodRef]bool{}
+	buf := make([]byte, 1500)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return replies, nil
+			}
+			return replies, err
+		}
+		port, ok := parseProbeReply(buf[:n])
+		if !ok {
+			continue
+		}
+		r := PodRef{Host: src.IP.String(), Port: port}
+		if !seen[r] {
+			seen[r] = true
+			replies = append(replies, r)
+		}
+	}
+}
+
+func parseProbeReply(b []byte) (int, bool) {
+	s := strings.TrimSpace(string(b))
+	if port, err := strconv.Atoi(s); err == nil {
+		return port, port > 0 && port < 65536
+	}
+	var reply struct {
+		Port int `json:"port"`
+	}
+	if err := json.Unmarshal([]byte(s), &reply); err != nil {
+		return 0, false
+	}
+	return reply.Port, reply.Port > 0 && reply.Port < 65536
+}
@@ -0,0 +1,359 @@
+package discover
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// --- Binary (protobuf) protocol ---
+//
+// Schema: proto/discover.proto. The encoder/decoder below implements the
+// handful of messages by hand so the package stays dependency-free.
+//
+// When Config.Protocol is ProtocolProtobuf, dialPod offers it after auth (and
+// compression):
+//
+//	-> {"type":"negotiate_protocol","protocol":"protobuf"}
+//	<- {"protocol":"protobuf"}
+//
+// It is only offered on binary-safe transports (length-prefixed framing, or
+// any transport once gzip is negotiated). Afterwards every message is an
+// Envelope; JSON commands without a schema ride in Envelope.json.
+
+// Protocol selects the message encoding.
+type Protocol int
+
+const (
+	ProtocolJSON     Protocol = iota // default
+	ProtocolProtobuf                 // binary envelopes, if the pod agrees
+)
+
+var errProtoBodyOnly = errors.New("protobuf reply has no JSON form")
+
+// protoTransport marks a connection that switched to protobuf envelopes.
+// sendMsg/readMsg keep working with JSON text by wrapping it in envelopes;
+// typed calls use sendEnvelope/readEnvelope with encoded bodies directly.
+type protoTransport struct {
+	inner msgTransport
+}
+
+type envelope struct {
+	Type string
+	JSON []byte
+	Body []byte
+}
+
+func negotiateProtocol(pc msgTransport) (msgTransport, error) {
+	if bs, ok := pc.(binarySafe); !ok || !bs.binarySafe() {
+		return pc, nil
+	}
+	if err := pc.sendMsg(`{"type":"negotiate_protocol","protocol":"protobuf"}`); err != nil {
+		return nil, err
+	}
+	resp, err := pc.readMsg()
+	if err != nil {
+		return nil, err
+	}
+	var reply struct {
+		Protocol string `json:"protocol"`
+	}
+	if json.Unmarshal([]byte(resp), &reply) != nil || reply.Protocol != "protobuf" {
+		return pc, nil
+	}
+	return &protoTransport{inner: pc}, nil
+}
+
+func (p *protoTransport) sendEnvelope(e envelope) error {
+	return p.inner.sendMsg(string(e.marshal()))
+}
+
+func (p *protoTransport) readEnvelope() (envelope, error) {
+	raw, err := p.inner.readMsg()
+	if err != nil {
+		return envelope{}, err
+	}
+	return unmarshalEnvelope([]byte(raw))
+}
+
+func (p *protoTransport) sendMsg(msg string) error {
+	var head struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal([]byte(msg), &head)
+	return p.sendEnvelope(envelope{Type: head.Type, JSON: []byte(msg)})
+}
+
+func (p *protoTransport) readMsg() (string, error) {
+	e, err := p.readEnvelope()
+	if err != nil {
+		return "", err
+	}
+	if e.JSON == nil {
+		return "", errProtoBodyOnly
+	}
+	return string(e.JSON), nil
+}
+
+func (p *protoTransport) setTimeout(d time.Duration) { p.inner.setTimeout(d) }
+func (p *protoTransport) Close() error               { return p.inner.Close() }
+
+// sendProtoRequest and readProtoReply are the typed call path on a protobuf
+// connection.
+func sendProtoRequest(p *protoTransport, cmdType string, req, resp any) error {
+	msg, err := encodeTypedRequest(cmdType, req, resp)
+	if err != nil {
+		return err
+	}
+	e := envelope{Type: cmdType}
+	if body, ok := protoEncodeRequest(req); ok {
+		e.Body = body
+	} else {
+		e.JSON = []byte(msg)
+	}
+	if err := p.sendEnvelope(e); err != nil {
+		return &CommandError{Type: cmdType, Stage: "send", Err: err}
+	}
+	return nil
+}
+
+func readProtoReply(p *protoTransport, cmdType string, resp any) error {
+	reply, err := p.readEnvelope()
+	if err != nil {
+		return &CommandError{Type: cmdType, Stage: "read", Err: err}
+	}
+	// proto3 leaves an empty body out entirely (a pod with no cubes answers
+	// get_cube_list with a bare envelope), so no payload is the zero response.
+	if reply.Body != nil || len(reply.JSON) == 0 {
+		if err := protoDecodeResponse(reply.Body, resp); err != nil {
+			return &CommandError{Type: cmdType, Stage: "decode", Err: err}
+		}
+		if v, ok := resp.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return &CommandError{Type: cmdType, Stage: "validate", Err: err}
+			}
+		}
+		return nil
+	}
+	return decodeTypedResponse(cmdType, string(reply.JSON), resp)
+}
+
+// --- message codecs ---
+
+func protoEncodeRequest(req any) ([]byte, bool) {
+	switch r := req.(type) {
+	case CubeListRequest, TimeRequest:
+		return []byte{}, true
+	case PlanetsRequest:
+		return appendString(nil, 1, r.Universe), true
+	case *PlanetsRequest:
+		return appendString(nil, 1, r.Universe), true
+	}
+	return nil, false
+}
+
+func protoDecodeResponse(b []byte, resp any) error {
+	switch r := resp.(type) {
+	case *CubeListResponse:
+		r.Cubes = nil
+		return walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+			if num == 1 && wt == wireBytes {
+				r.Cubes = append(r.Cubes, string(data))
+			}
+			return nil
+		})
+	case *TimeResponse:
+		return walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+			if num == 1 && wt == wireFixed64 {
+				r.Time = math.Float64frombits(v)
+			}
+			return nil
+		})
+	case *PlanetsResponse:
+		out := PlanetsResponse{}
+		err := walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+			if num != 1 || wt != wireBytes {
+				return nil
+			}
+			p, universe, err := decodePlanet(data)
+			if err != nil {
+				return err
+			}
+			out[universe] = append(out[universe], p)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*r = out
+		return nil
+	}
+	return fmt.Errorf("no protobuf schema for %T", resp)
+}
+
+func decodePlanet(b []byte) (Planet, string, error) {
+	var p Planet
+	var universe string
+	err := walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+		switch {
+		case num == 1 && wt == wireBytes:
+			p.Name = string(data)
+		case num == 2 && wt == wireBytes:
+			universe = string(data)
+		case num == 3 && wt == wireBytes:
+			vec, err := decodeVec3(data)
+			p.Position = vec
+			return err
+		case num == 4 && wt == wireVarint:
+			p.Seed = int(int64(v))
+		case num == 5 && wt == wireVarint:
+			p.BiomeType = int(int32(v))
+		case num == 6 && wt == wireBytes:
+			vec, err := decodeVec3(data)
+			p.ResourceLocations = append(p.ResourceLocations, vec)
+			return err
+		case num == 7 && wt == wireBytes:
+			vec, err := decodeVec3(data)
+			p.TreeLocations = append(p.TreeLocations, vec)
+			return err
+		}
+		return nil
+	})
+	return p, universe, err
+}
+
+func decodeVec3(b []byte) (map[string]float64, error) {
+	vec := map[string]float64{"x": 0, "y": 0, "z": 0}
+	axes := map[int]string{1: "x", 2: "y", 3: "z"}
+	err := walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+		if axis, ok := axes[num]; ok && wt == wireFixed64 {
+			vec[axis] = math.Float64frombits(v)
+		}
+		return nil
+	})
+	return vec, err
+}
+
+func (e envelope) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, e.Type)
+	if e.JSON != nil {
+		b = appendBytes(b, 2, e.JSON)
+	}
+	if e.Body != nil {
+		b = appendBytes(b, 3, e.Body)
+	}
+	return b
+}
+
+func unmarshalEnvelope(b []byte) (envelope, error) {
+	var e envelope
+	err := walkFields(b, func(num int, wt int, v uint64, data []byte) error {
+		if wt != wireBytes {
+			return nil
+		}
+		switch num {
+		case 1:
+			e.Type = string(data)
+		case 2:
+			e.JSON = append([]byte{}, data...)
+		case 3:
+			e.Body = append([]byte{}, data...)
+		}
+		return nil
+	})
+	return e, err
+}
+
+// --- wire format helpers ---
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errProtoTruncated = errors.New("truncated protobuf message")
+
+func appendTag(b []byte, num, wt int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wt))
+}
+
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendDouble(b []byte, num int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, num, wireFixed64), math.Float64bits(v))
+}
+
+func appendBytes(b []byte, num int, data []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendString(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, num, []byte(s))
+}
+
+// walkFields calls fn for each field; v holds varint/fixed values and data
+// the contents of length-delimited fields. Unknown fields are skipped.
+func walkFields(b []byte, fn func(num int, wt int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		num, wt := int(tag>>3), int(tag&7)
+		var v uint64
+		var data []byte
+		switch wt {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errProtoTruncated
+			}
+			v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errProtoTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errProtoTruncated
+			}
+			data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wt)
+		}
+		if err := fn(num, wt, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
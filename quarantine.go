@@ -0,0 +1,89 @@
+package discover
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// --------- AUTH QUARANTINE ---------
+
+// errQuarantined is the PodResult.Error of pods skipped while quarantined.
+var errQuarantined = errors.New("Quarantined")
+
+// QuarantinedPod is a pod skipped by scans after repeated auth failures.
+type QuarantinedPod struct {
+	PodRef
+	Failures int       // consecutive "Bad password" replies
+	Since    time.Time // when the pod was quarantined
+}
+
+func (q QuarantinedPod) String() string {
+	return fmt.Sprintf("%s quarantined after %d auth failures", q.PodRef, q.Failures)
+}
+
+type quarantine struct {
+	fails map[PodRef]int
+	pods  map[PodRef]QuarantinedPod
+}
+
+// isQuarantined reports whether pod is quarantined. d.mu must be held.
+func (d *Discover) isQuarantined(pod PodRef) bool {
+	_, ok := d.quarantine.pods[pod]
+	return ok
+}
+
+// recordAuthLocked updates the auth failure count for a scan result and
+// quarantines the pod once Config.QuarantineAfter consecutive scans were
+// rejected with a bad password. Network failures neither count nor reset.
+// d.mu must be held.
+func (d *Discover) recordAuthLocked(res PodResult) {
+	if d.Config.QuarantineAfter <= 0 {
+		return
+	}
+	q := &d.quarantine
+	switch {
+	case res.Success:
+		delete(q.fails, res.PodRef)
+	case res.Error == errBadPassword.Error():
+		if q.fails == nil {
+			q.fails = make(map[PodRef]int)
+			q.pods = make(map[PodRef]QuarantinedPod)
+		}
+		q.fails[res.PodRef]++
+		if n := q.fails[res.PodRef]; n >= d.Config.QuarantineAfter {
+			if _, ok := q.pods[res.PodRef]; !ok {
+				q.pods[res.PodRef] = QuarantinedPod{PodRef: res.PodRef, Failures: n, Since: time.Now()}
+			}
+		}
+	}
+}
+
+// Quarantined lists the pods currently skipped for repeated auth failures.
+func (d *Discover) Quarantined() []QuarantinedPod {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]QuarantinedPod, 0, len(d.quarantine.pods))
+	for _, q := range d.quarantine.pods {
+		out = append(out, q)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].Port < out[j].Port
+	})
+	return out
+}
+
+// Unquarantine returns a pod to the scan rotation, e.g. after fixing its
+// credentials. It reports whether the pod was quarantined.
+func (d *Discover) Unquarantine(pod PodRef) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.quarantine.pods[pod]
+	delete(d.quarantine.pods, pod)
+	delete(d.quarantine.fails, pod)
+	return ok
+}
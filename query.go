@@ -0,0 +1,117 @@
+package discover
+
+import "strings"
+
+// --------- PLANET QUERIES ---------
+
+// Query filters the discovered planets. Filters combine with AND; nothing is
+// read until a terminal method (Planets, Names, Count, First) runs, which
+// works on a copy taken under the lock.
+//
+//	d.Query().Host("node3").Biome(2).WithinRadius(center, 5000).Planets()
+type Query struct {
+	d     *Discover
+	preds []func(PlanetRecord) bool
+}
+
+// Query starts a query over Planets.
+func (d *Discover) Query() *Query {
+	return &Query{d: d}
+}
+
+// Where adds an arbitrary filter.
+func (q *Query) Where(fn func(PlanetRecord) bool) *Query {
+	q.preds = append(q.preds, fn)
+	return q
+}
+
+// Host keeps planets hosted on host (any port).
+func (q *Query) Host(host string) *Query {
+	return q.Where(func(p PlanetRecord) bool { return p.Host == host })
+}
+
+// Pod keeps planets reported by pod.
+func (q *Query) Pod(pod PodRef) *Query {
+	return q.Where(func(p PlanetRecord) bool { return p.PodRef == pod })
+}
+
+// Universe keeps planets from universe.
+func (q *Query) Universe(universe string) *Query {
+	return q.Where(func(p PlanetRecord) bool { return p.Universe == universe })
+}
+
+// Biome keeps planets of the given biome type.
+func (q *Query) Biome(biome int) *Query {
+	return q.Where(func(p PlanetRecord) bool { return p.BiomeType == biome })
+}
+
+// Seed keeps planets with the given seed.
+func (q *Query) Seed(seed int) *Query {
+	return q.Where(func(p PlanetRecord) bool { return p.Seed == seed })
+}
+
+// NamePrefix keeps planets whose name starts with prefix.
+func (q *Query) NamePrefix(prefix string) *Query {
+	return q.Where(func(p PlanetRecord) bool { return strings.HasPrefix(p.Name, prefix) })
+}
+
+// WithinRadius keeps planets no further than radius from center.
+func (q *Query) WithinRadius(center []float64, radius float64) *Query {
+	c := vec3f(center)
+	return q.Where(func(p PlanetRecord) bool { return distance3(p.Coordinates, c) <= radius })
+}
+
+// HasResources keeps planets with at least one resource location.
+func (q *Query) HasResources() *Query {
+	return q.Where(func(p PlanetRecord) bool { return len(p.ResourceLocations) > 0 })
+}
+
+func (q *Query) match(p PlanetRecord) bool {
+	for _, pred := range q.preds {
+		if !pred(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Planets returns the matching planets sorted by name.
+func (q *Query) Planets() []PlanetRecord {
+	var out []PlanetRecord
+	for _, p := range q.d.PlanetList() {
+		if q.match(p) {
+			out = append(out, p)
+		}
+	}
+	return out // PlanetList is sorted by name
+}
+
+// Names returns the names of the matching planets, sorted.
+func (q *Query) Names() []string {
+	planets := q.Planets()
+	names := make([]string, len(planets))
+	for i, p := range planets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Count returns how many planets match.
+func (q *Query) Count() int {
+	n := 0
+	for _, p := range q.d.PlanetList() {
+		if q.match(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// First returns the matching planet with the smallest name.
+func (q *Query) First() (PlanetRecord, bool) {
+	planets := q.Planets()
+	if len(planets) == 0 {
+		return PlanetRecord{}, false
+	}
+	return planets[0], true
+}
@@ -0,0 +1,199 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// --- Typed command registry ---
+//
+// Commands are registered with a Go request and response type. Calls marshal
+// the request with its "type" field filled in, decode the reply into the
+// response type and run its Validate method when it has one.
+
+// Validator is implemented by response types that can check themselves after decoding.
+type Validator interface {
+	Validate() error
+}
+
+// Request/response types for the built-in commands.
+type (
+	CubeListRequest  struct{}
+	CubeListResponse struct {
+		Cubes []string                   `json:"cubes"`
+		State map[string]json.RawMessage `json:"-"` // cubes listed as objects, see UnmarshalJSON
+	}
+	PlanetsRequest struct {
+		Universe string `json:"universe,omitempty"` // empty asks for every universe
+	}
+	PlanetsResponse map[string][]Planet // universe -> planets
+)
+
+type commandSpec struct {
+	req, resp reflect.Type
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]commandSpec{}
+)
+
+func init() {
+	RegisterCommand(StepCubes, CubeListRequest{}, CubeListResponse{})
+	RegisterCommand(StepPlanets, PlanetsRequest{}, PlanetsResponse{})
+}
+
+// RegisterCommand registers cmdType with example values of its request and
+// response types, e.g. RegisterCommand("get_time", TimeRequest{}, TimeResponse{}).
+// Registering the same type again replaces the previous entry.
+func RegisterCommand(cmdType string, req, resp any) error {
+	if cmdType == "" {
+		return fmt.Errorf("command type must not be empty")
+	}
+	if req == nil || resp == nil {
+		return fmt.Errorf("command %s: request and response types are required", cmdType)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[cmdType] = commandSpec{req: derefType(reflect.TypeOf(req)), resp: derefType(reflect.TypeOf(resp))}
+	return nil
+}
+
+// RegisteredCommands lists the registered command types.
+func RegisteredCommands() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for t := range registry {
+		out = append(out, t)
+	}
+	return out
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// CommandError reports which stage of a typed call failed.
+type CommandError struct {
+	Type  string
+	Stage string // "lookup", "encode", "send", "read", "decode" or "validate"
+	Err   error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command %s: %s: %v", e.Type, e.Stage, e.Err)
+}
+
+func (e *CommandError) Unwrap() error { return e.Err }
+
+// Call sends a registered command. req must be of the registered request type
+// (or a pointer to it) and resp a pointer to the registered response type.
+// The reply is decoded as it streams in, except on multiplexed clients where
+// replies are routed as whole messages.
+func (c *PodClient) Call(ctx context.Context, cmdType string, req, resp any) error {
+	if c.cfg.Multiplex {
+		return callCommand(func(msg string) (string, error) {
+			return c.roundTrip(ctx, msg)
+		}, cmdType, req, resp)
+	}
+	return c.withConn(ctx, func(t msgTransport) error {
+		return callCommandStream(t, cmdType, req, resp)
+	})
+}
+
+// Request sends payload (see SendCommand) and decodes the reply into a T,
+// for custom commands that don't warrant a RegisterCommand entry:
+//
+//	stats, err := discover.Request[ServerStats](client, map[string]string{"type": "get_stats"})
+func Request[T any](client *PodClient, payload any) (T, error) {
+	return RequestContext[T](context.Background(), client, payload)
+}
+
+// RequestContext is Request with a context for cancellation.
+func RequestContext[T any](ctx context.Context, client *PodClient, payload any) (T, error) {
+	var out T
+	raw, err := client.SendCommand(ctx, payload)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return out, fmt.Errorf("decode reply into %T: %w", out, err)
+	}
+	if v, ok := any(&out).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// callCommand runs a typed call over any send-one-read-one function.
+func callCommand(roundTrip func(string) (string, error), cmdType string, req, resp any) error {
+	msg, err := encodeTypedRequest(cmdType, req, resp)
+	if err != nil {
+		return err
+	}
+	raw, err := roundTrip(msg)
+	if err != nil {
+		return &CommandError{Type: cmdType, Stage: "send", Err: err}
+	}
+	return decodeTypedResponse(cmdType, raw, resp)
+}
+
+func lookupCommand(cmdType string) (commandSpec, error) {
+	registryMu.RLock()
+	spec, ok := registry[cmdType]
+	registryMu.RUnlock()
+	if !ok {
+		return commandSpec{}, &CommandError{Type: cmdType, Stage: "lookup", Err: fmt.Errorf("not registered")}
+	}
+	return spec, nil
+}
+
+func encodeTypedRequest(cmdType string, req, resp any) (string, error) {
+	spec, err := lookupCommand(cmdType)
+	if err != nil {
+		return "", err
+	}
+	if req == nil || derefType(reflect.TypeOf(req)) != spec.req {
+		return "", &CommandError{Type: cmdType, Stage: "encode", Err: fmt.Errorf("request must be %s, got %T", spec.req, req)}
+	}
+	if rt := reflect.TypeOf(resp); rt == nil || rt.Kind() != reflect.Pointer || rt.Elem() != spec.resp {
+		return "", &CommandError{Type: cmdType, Stage: "encode", Err: fmt.Errorf("response must be *%s, got %T", spec.resp, resp)}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", &CommandError{Type: cmdType, Stage: "encode", Err: err}
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", &CommandError{Type: cmdType, Stage: "encode", Err: fmt.Errorf("request must encode to a JSON object: %w", err)}
+	}
+	typ, _ := json.Marshal(cmdType)
+	fields["type"] = typ
+	msg, err := json.Marshal(fields)
+	if err != nil {
+		return "", &CommandError{Type: cmdType, Stage: "encode", Err: err}
+	}
+	return string(msg), nil
+}
+
+func decodeTypedResponse(cmdType, raw string, resp any) error {
+	if err := json.Unmarshal([]byte(raw), resp); err != nil {
+		return &CommandError{Type: cmdType, Stage: "decode", Err: err}
+	}
+	if v, ok := resp.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return &CommandError{Type: cmdType, Stage: "validate", Err: err}
+		}
+	}
+	return nil
+}
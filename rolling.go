@@ -0,0 +1,99 @@
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BroadcastResult records what happened to one pod during a RollingBroadcast.
+type BroadcastResult struct {
+	PodRef
+	Response string // raw reply to the command
+	Error    string // command error or the pod's "error" reply, empty on success
+	Healthy  bool   // pod answered the post-batch health check
+}
+
+func (r BroadcastResult) String() string {
+	return fmt.Sprintf("%s healthy=%v err=%q reply=%s", r.PodRef, r.Healthy, r.Error, r.Response)
+}
+
+// RollingBroadcast sends payload to every configured pod that isn't
+// quarantined, batchSize pods at a time. After each batch the pods are
+// health-checked (reconnect + auth) and the rollout stops if any of them
+// failed the command or the check, so a bad command never reaches the whole
+// cluster. pause is waited between batches. The results gathered so far are
+// returned together with the error.
+func (d *Discover) RollingBroadcast(payload any, batchSize int, pause time.Duration) ([]BroadcastResult, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if _, err := encodeCommand(payload); err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+
+	var addrs []PodRef
+	all := d.podAddrs()
+	d.mu.Lock()
+	for _, addr := range all {
+		if !d.isQuarantined(addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	d.mu.Unlock()
+	results := make([]BroadcastResult, 0, len(addrs))
+	for start := 0; start < len(addrs); start += batchSize {
+		if start > 0 && pause > 0 {
+			time.Sleep(pause)
+		}
+		end := min(start+batchSize, len(addrs))
+		batch := d.broadcastBatch(addrs[start:end], payload)
+		results = append(results, batch...)
+
+		failed, unhealthy := 0, 0
+		for _, r := range batch {
+			if r.Error != "" {
+				failed++
+			}
+			if !r.Healthy {
+				unhealthy++
+			}
+		}
+		if failed > 0 || unhealthy > 0 {
+			return results, fmt.Errorf("rolling broadcast halted after batch %d: %d command failure(s), %d pod(s) unhealthy", start/batchSize+1, failed, unhealthy)
+		}
+	}
+	return results, nil
+}
+
+// broadcastBatch sends payload to each pod in the batch concurrently, then
+// health-checks them. Results keep the order of addrs.
+func (d *Discover) broadcastBatch(addrs []PodRef, payload any) []BroadcastResult {
+	results := make([]BroadcastResult, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr PodRef) {
+			defer wg.Done()
+			r := BroadcastResult{PodRef: addr}
+			resp, err := sendPodCommand(d.Config, addr, payload)
+			r.Response = resp
+			var refusal struct {
+				Error string `json:"error"`
+			}
+			if err != nil {
+				r.Error = err.Error()
+			} else if json.Unmarshal([]byte(resp), &refusal) == nil && refusal.Error != "" {
+				r.Error = "pod refused: " + refusal.Error
+			}
+			if pc, err := dialPod(d.Config, addr); err == nil {
+				pc.Close()
+				r.Healthy = true
+			}
+			results[i] = r
+		}(i, addr)
+	}
+	wg.Wait()
+	return results
+}
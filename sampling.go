@@ -0,0 +1,242 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// --------- SPHERE SAMPLING ---------
+
+// Every random helper takes a seed or a *rand.Rand and nothing else random,
+// so layouts are reproducible in tests and replays. The rest of the spawn and
+// ID helpers (FibonacciSphere, rings, GenerateUnitID, ...) are deterministic.
+
+// poissonCandidates is how many random candidates Mitchell's best-candidate
+// draws per accepted point; poissonRounds is how many tries a point gets to
+// clear the minimum separation before sampling stops.
+const (
+	poissonCandidates = 30
+	poissonRounds     = 4
+)
+
+// PoissonSphere places up to n points on a sphere around center so that no two
+// are closer than minAngle degrees (measured from the center), using Mitchell's
+// best-candidate sampling. The same seed gives the same points. Fewer than n
+// points are returned once no candidate clears minAngle, i.e. the sphere is full.
+func PoissonSphere(n int, radius float64, center []float64, minAngle float64, seed int64) [][]float64 {
+	return PoissonSphereRand(n, radius, center, minAngle, rand.New(rand.NewSource(seed)))
+}
+
+// PoissonSphereRand is PoissonSphere drawing from rng, so one generator can be
+// threaded through a whole layout (or replay) instead of reseeding per call.
+func PoissonSphereRand(n int, radius float64, center []float64, minAngle float64, rng *rand.Rand) [][]float64 {
+	defer perfTrack("PoissonSphere")()
+	maxDot := math.Cos(minAngle * math.Pi / 180)
+	var units [][3]float64
+	for len(units) < n {
+		best, bestDot, ok := [3]float64{}, 2.0, false
+		for try := 0; try < poissonCandidates*poissonRounds && !(ok && try >= poissonCandidates); try++ {
+			c := randomUnit(rng)
+			worst := -1.0 // largest dot = nearest accepted point
+			for _, u := range units {
+				if dot := c[0]*u[0] + c[1]*u[1] + c[2]*u[2]; dot > worst {
+					worst = dot
+				}
+			}
+			if worst < bestDot {
+				best, bestDot = c, worst
+			}
+			ok = bestDot <= maxDot
+		}
+		if !ok {
+			break
+		}
+		units = append(units, best)
+	}
+
+	points := make([][]float64, len(units))
+	for i, u := range units {
+		points[i] = []float64{
+			center[0] + u[0]*radius,
+			center[1] + u[1]*radius,
+			center[2] + u[2]*radius,
+		}
+	}
+	return points
+}
+
+// RandomSpherePoints returns n points uniformly distributed at random on a
+// sphere around center. The same seed gives the same points.
+func RandomSpherePoints(n int, radius float64, center []float64, seed int64) [][]float64 {
+	return RandomSpherePointsRand(n, radius, center, rand.New(rand.NewSource(seed)))
+}
+
+// RandomSpherePointsRand is RandomSpherePoints drawing from rng.
+func RandomSpherePointsRand(n int, radius float64, center []float64, rng *rand.Rand) [][]float64 {
+	points := make([][]float64, n)
+	for i := range points {
+		u := randomUnit(rng)
+		points[i] = []float64{
+			center[0] + u[0]*radius,
+			center[1] + u[1]*radius,
+			center[2] + u[2]*radius,
+		}
+	}
+	return points
+}
+
+// randomUnit returns a uniformly distributed point on the unit sphere.
+func randomUnit(rng *rand.Rand) [3]float64 {
+	z := 2*rng.Float64() - 1
+	theta := 2 * math.Pi * rng.Float64()
+	r := math.Sqrt(1 - z*z)
+	return [3]float64{r * math.Cos(theta), r * math.Sin(theta), z}
+}
+
+// GeneratePoissonSpawns is GenerateSpawnPositions with PoissonSphere spacing:
+// irregular, but never closer than minAngle degrees. Cached like
+// GenerateSpawnPositions.
+func (d *Discover) GeneratePoissonSpawns(planetName string, n int, radius, minAngle float64, seed int64) ([][]float64, error) {
+	v, err := d.Derived(planetName, fmt.Sprintf("spawn/poisson/%d/%g/%g/%d", n, radius, minAngle, seed), func(planet PlanetRecord) (any, error) {
+		return PoissonSphere(n, radius, planet.Coordinates[:], minAngle, seed), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPoints(v.([][]float64)), nil
+}
+
+// --- rings and bands ---
+
+// LatitudeRing places n points evenly spaced in longitude on the circle at
+// latitude degrees (0 = equator, +90 = the +Y pole, as in FibonacciSphere) of
+// a sphere around center. inclination tilts the ring's pole about the X axis
+// by that many degrees, so LatitudeRing(n, r, c, 0, 30) is an equatorial ring
+// inclined 30 degrees. The first point is at longitude 0 (+X).
+func LatitudeRing(n int, radius float64, center []float64, latitude, inclination float64) [][]float64 {
+	return ringPoints(n, radius, center, latitude, inclination, 0)
+}
+
+// LatitudeBands stacks bands rings of perBand points at evenly spaced
+// latitudes strictly between the poles, ordered south to north. Alternate
+// bands are offset by half a longitude step so points don't line up in
+// columns. inclination tilts every band as in LatitudeRing.
+func LatitudeBands(bands, perBand int, radius float64, center []float64, inclination float64) [][]float64 {
+	var points [][]float64
+	for b := 0; b < bands; b++ {
+		lat := -90 + 180*float64(b+1)/float64(bands+1)
+		phase := 0.0
+		if b%2 == 1 {
+			phase = 0.5
+		}
+		points = append(points, ringPoints(perBand, radius, center, lat, inclination, phase)...)
+	}
+	return points
+}
+
+// ringPoints is LatitudeRing with the first point shifted by phase longitude
+// steps.
+func ringPoints(n int, radius float64, center []float64, latitude, inclination, phase float64) [][]float64 {
+	lat := latitude * math.Pi / 180
+	sinI, cosI := math.Sincos(inclination * math.Pi / 180)
+	points := make([][]float64, n)
+	for i := range points {
+		lon := 2 * math.Pi * (float64(i) + phase) / float64(n)
+		x := math.Cos(lat) * math.Cos(lon)
+		y := math.Sin(lat)
+		z := math.Cos(lat) * math.Sin(lon)
+		y, z = y*cosI-z*sinI, y*sinI+z*cosI
+		points[i] = []float64{
+			center[0] + x*radius,
+			center[1] + y*radius,
+			center[2] + z*radius,
+		}
+	}
+	return points
+}
+
+// GenerateRingSpawns is LatitudeRing around a planet, cached like
+// GenerateSpawnPositions.
+func (d *Discover) GenerateRingSpawns(planetName string, n int, radius, latitude, inclination float64) ([][]float64, error) {
+	v, err := d.Derived(planetName, fmt.Sprintf("spawn/ring/%d/%g/%g/%g", n, radius, latitude, inclination), func(planet PlanetRecord) (any, error) {
+		return LatitudeRing(n, radius, planet.Coordinates[:], latitude, inclination), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPoints(v.([][]float64)), nil
+}
+
+// --- spacing constraints ---
+
+// spacingGrowth caps how far GenerateSpacedSpawnPositions densifies its
+// candidate sphere: up to spacingGrowth*n Fibonacci points.
+const spacingGrowth = 8
+
+// GenerateSpacedSpawnPositions is GenerateSpawnPositions with constraints: no
+// two returned points are closer than minSpacing, and none is closer than
+// minSpacing to a point in occupied (units already placed, say). When the
+// plain n-point layout breaks a constraint, it retries with denser candidate
+// spheres and picks a spread-out subset that fits. Fewer than n points are
+// returned if that many can't fit; the length says how many did.
+func (d *Discover) GenerateSpacedSpawnPositions(planetName string, n int, radius, minSpacing float64, occupied [][]float64) ([][]float64, error) {
+	var best [][]float64
+	for m := n; m > 0 && m <= n*spacingGrowth; m *= 2 {
+		candidates, err := d.GenerateSpawnPositions(planetName, m, radius)
+		if err != nil {
+			return nil, err
+		}
+		if picked := spaceOut(candidates, n, minSpacing, occupied); len(picked) > len(best) {
+			best = picked
+		}
+		if len(best) == n {
+			break
+		}
+	}
+	if best == nil {
+		best = [][]float64{}
+	}
+	return best, nil
+}
+
+// spaceOut greedily picks up to n candidates that keep minSpacing from each
+// other and from occupied. Candidates are visited in golden-ratio order so a
+// partial pick is spread over the whole sphere rather than bunched at the top
+// of the Fibonacci spiral; the result keeps candidate order.
+func spaceOut(candidates [][]float64, n int, minSpacing float64, occupied [][]float64) [][]float64 {
+	const golden = 0.6180339887498949
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	frac := func(i int) float64 { _, f := math.Modf(float64(i) * golden); return f }
+	sort.SliceStable(order, func(a, b int) bool { return frac(order[a]) < frac(order[b]) })
+
+	clear := func(p []float64, others [][]float64) bool {
+		for _, o := range others {
+			if distance3(vec3f(p), vec3f(o)) < minSpacing {
+				return false
+			}
+		}
+		return true
+	}
+	var picked []int
+	var placed [][]float64
+	for _, i := range order {
+		if len(picked) == n {
+			break
+		}
+		if p := candidates[i]; clear(p, occupied) && clear(p, placed) {
+			picked = append(picked, i)
+			placed = append(placed, p)
+		}
+	}
+	sort.Ints(picked)
+	out := make([][]float64, len(picked))
+	for k, i := range picked {
+		out[k] = candidates[i]
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package discover
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// --------- COORDINATE SANITIZATION ---------
+
+// CoordinatePolicy decides what happens to non-finite (NaN or ±Inf)
+// coordinates on ingest. A single NaN planet would otherwise poison
+// FindClosestPlanet and every distance-based utility.
+type CoordinatePolicy int
+
+const (
+	CoordReject CoordinatePolicy = iota // drop the planet (default); non-finite resource/tree locations are dropped
+	CoordClamp                          // NaN becomes 0, ±Inf becomes ±MaxClampedCoordinate
+	CoordKeep                           // keep values as reported (only warn)
+)
+
+// MaxClampedCoordinate is what CoordClamp turns ±Inf into: large but far from
+// overflowing when squared in distance math.
+const MaxClampedCoordinate = 1e12
+
+func finite(v float64) bool { return !math.IsNaN(v) && !math.IsInf(v, 0) }
+
+func clampCoordinate(v float64) float64 {
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case math.IsInf(v, 1):
+		return MaxClampedCoordinate
+	case math.IsInf(v, -1):
+		return -MaxClampedCoordinate
+	}
+	return v
+}
+
+// sanitizeVec applies policy to one coordinate map in place. It returns
+// false if the vector must be dropped and the axes that weren't finite.
+func sanitizeVec(policy CoordinatePolicy, v map[string]float64) (bool, []string) {
+	var bad []string
+	for axis, x := range v {
+		if !finite(x) {
+			bad = append(bad, fmt.Sprintf("%s=%v", axis, x))
+		}
+	}
+	if len(bad) == 0 {
+		return true, nil
+	}
+	sort.Strings(bad)
+	switch policy {
+	case CoordReject:
+		return false, bad
+	case CoordClamp:
+		for axis, x := range v {
+			v[axis] = clampCoordinate(x)
+		}
+	}
+	return true, bad
+}
+
+// sanitizePlanet applies policy to a planet's position and locations,
+// appending a warning for each fix. It returns false if the planet is rejected.
+func sanitizePlanet(policy CoordinatePolicy, p *Planet, pod PodRef, warnings *[]Warning) bool {
+	warn := func(msg string) {
+		*warnings = append(*warnings, Warning{Pod: pod, Kind: WarnBadCoordinates, Command: StepPlanets, Planet: p.Name, Message: msg})
+	}
+	action := map[CoordinatePolicy]string{CoordReject: "rejected", CoordClamp: "clamped", CoordKeep: "kept"}[policy]
+	if ok, bad := sanitizeVec(policy, p.Position); len(bad) > 0 {
+		warn(fmt.Sprintf("Position %v not finite; planet %s", bad, action))
+		if !ok {
+			return false
+		}
+	}
+	fixLocs := func(field string, locs []map[string]float64) []map[string]float64 {
+		kept := locs[:0]
+		for i, l := range locs {
+			ok, bad := sanitizeVec(policy, l)
+			if len(bad) > 0 {
+				verb := action
+				if !ok {
+					verb = "dropped"
+				}
+				warn(fmt.Sprintf("%s[%d] %v not finite; %s", field, i, bad, verb))
+			}
+			if ok {
+				kept = append(kept, l)
+			}
+		}
+		return kept
+	}
+	p.ResourceLocations = fixLocs("ResourceLocations", p.ResourceLocations)
+	p.TreeLocations = fixLocs("TreeLocations", p.TreeLocations)
+	return true
+}
+
+// CoordinateReport returns, per pod, the coordinate problems found in its
+// latest scan and what was done about them.
+func (d *Discover) CoordinateReport() map[PodRef][]Warning {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	latest := map[PodRef]PodResult{}
+	for _, res := range d.Results {
+		latest[res.PodRef] = res
+	}
+	report := map[PodRef][]Warning{}
+	for pod, res := range latest {
+		for _, w := range res.Warnings {
+			if w.Kind == WarnBadCoordinates {
+				report[pod] = append(report[pod], w)
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,138 @@
+package discover
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// --------- 3D SCENE EXPORT ---------
+
+// Scene vertex kinds.
+const (
+	ScenePlanet = "planet"
+	SceneSpawn  = "spawn"
+)
+
+// SceneVertex is one point of an exported scene. OBJ and PLY files carry only
+// positions (and colors); the metadata sidecar lists these records, indexed
+// like the vertices (0-based).
+type SceneVertex struct {
+	Index     int        `json:"index"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"` // planet name; for spawns, the planet spawned around
+	Position  [3]float64 `json:"position"`
+	Universe  string     `json:"universe,omitempty"`
+	Pod       string     `json:"pod,omitempty"`
+	Seed      int        `json:"seed,omitempty"`
+	BiomeType int        `json:"biome_type,omitempty"`
+	Free      *bool      `json:"free,omitempty"` // spawns only
+}
+
+// SceneVertices lists the planets (sorted by name) followed by the points of
+// each plan, in the order the scene exporters write them. Nil plans are
+// skipped.
+func (d *Discover) SceneVertices(plans ...*SpawnPlan) []SceneVertex {
+	planets := d.PlanetList()
+	sort.Slice(planets, func(i, j int) bool { return planets[i].Name < planets[j].Name })
+	var out []SceneVertex
+	for _, p := range planets {
+		out = append(out, SceneVertex{
+			Index: len(out), Kind: ScenePlanet, Name: p.Name, Position: p.Coordinates,
+			Universe: p.Universe, Pod: p.PodRef.String(), Seed: p.Seed, BiomeType: p.BiomeType,
+		})
+	}
+	for _, plan := range plans {
+		if plan == nil {
+			continue
+		}
+		for _, pt := range plan.Points {
+			free := pt.Free
+			out = append(out, SceneVertex{
+				Index: len(out), Kind: SceneSpawn, Name: plan.Planet.Name,
+				Position: vec3f(pt.Position), Universe: plan.Planet.Universe, Free: &free,
+			})
+		}
+	}
+	return out
+}
+
+// vec3f copies up to three axes of a spawn position.
+func vec3f(v []float64) [3]float64 {
+	var out [3]float64
+	copy(out[:], v)
+	return out
+}
+
+// sceneColor marks planets white, free spawns green and blocked spawns red.
+func sceneColor(v SceneVertex) (r, g, b int) {
+	switch {
+	case v.Kind == ScenePlanet:
+		return 255, 255, 255
+	case v.Free != nil && *v.Free:
+		return 0, 200, 0
+	default:
+		return 220, 0, 0
+	}
+}
+
+func sceneFloat(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+// ExportScenePLY writes the planets and spawn points as an ASCII PLY point
+// cloud with per-vertex colors, for MeshLab or Blender.
+func (d *Discover) ExportScenePLY(w io.Writer, plans ...*SpawnPlan) error {
+	verts := d.SceneVertices(plans...)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ply\nformat ascii 1.0\ncomment discover scene (metadata in sidecar JSON)\n")
+	fmt.Fprintf(bw, "element vertex %d\n", len(verts))
+	fmt.Fprintf(bw, "property double x\nproperty double y\nproperty double z\n")
+	fmt.Fprintf(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\nend_header\n")
+	for _, v := range verts {
+		r, g, b := sceneColor(v)
+		fmt.Fprintf(bw, "%s %s %s %d %d %d\n", sceneFloat(v.Position[0]), sceneFloat(v.Position[1]), sceneFloat(v.Position[2]), r, g, b)
+	}
+	return bw.Flush()
+}
+
+// ExportSceneOBJ writes the planets and spawn points as OBJ point elements,
+// grouped into "planets" and "spawns_<planet>" objects.
+func (d *Discover) ExportSceneOBJ(w io.Writer, plans ...*SpawnPlan) error {
+	verts := d.SceneVertices(plans...)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# discover scene: %d vertices (metadata in sidecar JSON)\n", len(verts))
+	group := ""
+	for _, v := range verts {
+		g := "planets"
+		if v.Kind == SceneSpawn {
+			g = "spawns_" + objName(v.Name)
+		}
+		if g != group {
+			fmt.Fprintf(bw, "o %s\n", g)
+			group = g
+		}
+		fmt.Fprintf(bw, "v %s %s %s\np -1\n", sceneFloat(v.Position[0]), sceneFloat(v.Position[1]), sceneFloat(v.Position[2]))
+	}
+	return bw.Flush()
+}
+
+// objName keeps OBJ object names to one token.
+func objName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c == '#' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// ExportSceneMetadata writes the sidecar for ExportScenePLY/ExportSceneOBJ:
+// a JSON array of SceneVertex records in vertex order.
+func (d *Discover) ExportSceneMetadata(w io.Writer, plans ...*SpawnPlan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.SceneVertices(plans...))
+}
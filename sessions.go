@@ -0,0 +1,57 @@
+package discover
+
+import (
+	"fmt"
+	"time"
+)
+
+// --------- SCAN SESSIONS ---------
+
+// Session is one ScanAll (or RescanPods) run. Every PodResult it produced
+// carries its ID in PodResult.Session.
+type Session struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Pods       int       `json:"pods"`
+	Succeeded  int       `json:"succeeded"`
+	Rescan     bool      `json:"rescan,omitempty"` // RescanPods of a subset of pods
+}
+
+// beginSessionLocked allocates a session ID. d.mu must be held.
+func (d *Discover) beginSessionLocked(started time.Time) string {
+	d.sessionSeq++
+	return fmt.Sprintf("%s-%d", started.UTC().Format("20060102T150405Z"), d.sessionSeq)
+}
+
+// endSessionLocked records a finished session. d.mu must be held.
+func (d *Discover) endSessionLocked(id string, started time.Time, results []PodResult, rescan bool) {
+	s := Session{ID: id, StartedAt: started, FinishedAt: time.Now(), Pods: len(results), Rescan: rescan}
+	for _, r := range results {
+		if r.Success {
+			s.Succeeded++
+		}
+	}
+	d.sessions = append(d.sessions, s)
+}
+
+// Sessions lists the scan runs of this Discover, oldest first.
+func (d *Discover) Sessions() []Session {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Session(nil), d.sessions...)
+}
+
+// SessionResults returns the results of one session still in Results;
+// RescanPods replaces a pod's earlier results.
+func (d *Discover) SessionResults(id string) []PodResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []PodResult
+	for _, r := range d.Results {
+		if r.Session == id {
+			out = append(out, r)
+		}
+	}
+	return out
+}
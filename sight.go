@@ -0,0 +1,105 @@
+package discover
+
+import "math"
+
+// --------- LINE OF SIGHT ---------
+
+// planetSpheres returns every planet with its blocking radius: radii[name] if
+// present, else PlanetRadius. Planets with no radius are left out.
+func (d *Discover) planetSpheres(radii map[string]float64) ([]PlanetRecord, []float64) {
+	var planets []PlanetRecord
+	var rs []float64
+	for _, p := range d.PlanetList() {
+		r, ok := radii[p.Name]
+		if !ok {
+			r, _ = d.PlanetRadius(p.Name)
+		}
+		if r > 0 && finite3(p.Coordinates) {
+			planets = append(planets, p)
+			rs = append(rs, r)
+		}
+	}
+	return planets, rs
+}
+
+// HasLineOfSight reports whether the segment from a to b misses every planet
+// sphere. Radii come from planetRadii, falling back to PlanetRadius for
+// planets it doesn't list; planets with neither never block. A segment that
+// only touches a sphere, from a unit standing on its surface say, is clear.
+func (d *Discover) HasLineOfSight(a, b []float64, planetRadii map[string]float64) bool {
+	p0, p1 := vec3f(a), vec3f(b)
+	planets, radii := d.planetSpheres(planetRadii)
+	for i, p := range planets {
+		if segmentDist2(p0, p1, p.Coordinates) < radii[i]*radii[i]*(1-1e-12) {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentDist2 is the squared distance from c to the segment ab.
+func segmentDist2(a, b, c [3]float64) float64 {
+	ab, ac := sub3(b, a), sub3(c, a)
+	t := 0.0
+	if l2 := dot3(ab, ab); l2 > 0 {
+		t = math.Max(0, math.Min(1, dot3(ac, ab)/l2))
+	}
+	return dist2(c, [3]float64{a[0] + t*ab[0], a[1] + t*ab[1], a[2] + t*ab[2]})
+}
+
+// --- rays ---
+
+// RayHit is where a ray first meets a planet sphere.
+type RayHit struct {
+	Planet   string
+	Distance float64   // from the ray origin
+	Point    []float64 // hit position
+	Normal   []float64 // outward unit surface normal at Point
+}
+
+// IntersectRaySphere returns the distance along the ray from origin in
+// direction dir (any length) to the first point on the sphere, or false if it
+// misses. A ray starting inside the sphere hits where it leaves.
+func IntersectRaySphere(origin, dir, center []float64, radius float64) (float64, bool) {
+	u := normalize3(vec3f(dir))
+	if u == ([3]float64{}) {
+		return 0, false
+	}
+	oc := sub3(vec3f(origin), vec3f(center))
+	b := dot3(oc, u)
+	disc := b*b - (dot3(oc, oc) - radius*radius)
+	if disc < 0 {
+		return 0, false
+	}
+	s := math.Sqrt(disc)
+	if t := -b - s; t >= 0 {
+		return t, true
+	}
+	if t := -b + s; t >= 0 {
+		return t, true
+	}
+	return 0, false
+}
+
+// Raycast returns the nearest planet hit by the ray from origin along dir,
+// for picking and projectile checks. Radii are resolved as in HasLineOfSight.
+func (d *Discover) Raycast(origin, dir []float64, planetRadii map[string]float64) (RayHit, bool) {
+	planets, radii := d.planetSpheres(planetRadii)
+	best, bestT := -1, math.Inf(1)
+	for i, p := range planets {
+		if t, ok := IntersectRaySphere(origin, dir, p.Coordinates[:], radii[i]); ok && t < bestT {
+			best, bestT = i, t
+		}
+	}
+	if best < 0 {
+		return RayHit{}, false
+	}
+	u, o := normalize3(vec3f(dir)), vec3f(origin)
+	point := []float64{o[0] + bestT*u[0], o[1] + bestT*u[1], o[2] + bestT*u[2]}
+	return RayHit{
+		Planet:   planets[best].Name,
+		Distance: bestT,
+		Point:    point,
+		Normal:   OutwardNormal(planets[best].Coordinates[:], point),
+	}, true
+}
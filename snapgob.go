@@ -0,0 +1,150 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --------- BINARY SNAPSHOTS ---------
+
+// SnapshotEncoding selects how snapshots are serialized.
+type SnapshotEncoding int
+
+const (
+	SnapshotJSON    SnapshotEncoding = iota // indented JSON (default)
+	SnapshotGob                             // encoding/gob behind a magic header; several times faster for large worlds
+	SnapshotMsgPack                         // MessagePack with the JSON field names; compact and language-neutral
+)
+
+// ContentType is the media type an encoding is served as.
+func (e SnapshotEncoding) ContentType() string {
+	switch e {
+	case SnapshotGob:
+		return "application/x-gob"
+	case SnapshotMsgPack:
+		return "application/msgpack"
+	}
+	return "application/json"
+}
+
+// snapshotMediaTypes maps accepted media types to encodings.
+var snapshotMediaTypes = map[string]SnapshotEncoding{
+	"application/json":        SnapshotJSON,
+	"application/x-gob":       SnapshotGob,
+	"application/msgpack":     SnapshotMsgPack,
+	"application/x-msgpack":   SnapshotMsgPack,
+	"application/vnd.msgpack": SnapshotMsgPack,
+}
+
+// gobMagic starts every gob snapshot so loaders can tell it from JSON.
+const gobMagic = "DSCVSNAP\x00gob\n"
+
+// SaveSnapshotAs writes the current state in the given encoding.
+func (d *Discover) SaveSnapshotAs(w io.Writer, enc SnapshotEncoding) error {
+	d.mu.Lock()
+	s := d.snapshotLocked()
+	d.mu.Unlock()
+	return encodeSnapshot(w, s, enc)
+}
+
+func encodeSnapshot(w io.Writer, s Snapshot, enc SnapshotEncoding) error {
+	switch enc {
+	case SnapshotJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(s)
+	case SnapshotGob:
+		if _, err := io.WriteString(w, gobMagic); err != nil {
+			return err
+		}
+		return gob.NewEncoder(w).Encode(s)
+	case SnapshotMsgPack:
+		return encodeMsgpackSnapshot(w, s)
+	}
+	return fmt.Errorf("snapshot: unknown encoding %d", enc)
+}
+
+// decodeSnapshot reads any encoding SaveSnapshotAs writes.
+func decodeSnapshot(r io.Reader) (Snapshot, error) {
+	var s Snapshot
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(gobMagic)); bytes.Equal(head, []byte(gobMagic)) {
+		br.Discard(len(gobMagic))
+		if err := gob.NewDecoder(br).Decode(&s); err != nil {
+			return s, fmt.Errorf("snapshot: %w", err)
+		}
+		return s, nil
+	}
+	if head, _ := br.Peek(1); len(head) == 1 && (head[0]&0xf0 == 0x80 || head[0] == 0xde || head[0] == 0xdf) {
+		data, err := io.ReadAll(br)
+		if err == nil {
+			s, err = decodeMsgpackSnapshot(data)
+		}
+		if err != nil {
+			return s, fmt.Errorf("snapshot: %w", err)
+		}
+		return s, nil
+	}
+	if err := json.NewDecoder(br).Decode(&s); err != nil {
+		return s, fmt.Errorf("snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// --- HTTP ---
+
+// SnapshotHandler serves the current state on GET, encoded according to the
+// request's Accept header: JSON (default), MessagePack (application/msgpack)
+// or gob (application/x-gob).
+func (d *Discover) SnapshotHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		enc := NegotiateSnapshotEncoding(r.Header.Get("Accept"))
+		var buf bytes.Buffer
+		if err := d.SaveSnapshotAs(&buf, enc); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", enc.ContentType())
+		w.Header().Set("Vary", "Accept")
+		w.Write(buf.Bytes())
+	})
+}
+
+// NegotiateSnapshotEncoding picks the encoding with the highest q value in
+// an Accept header, falling back to JSON.
+func NegotiateSnapshotEncoding(accept string) SnapshotEncoding {
+	best, bestQ := SnapshotJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		enc, ok := snapshotMediaTypes[mt]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,149 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// --------- SNAPSHOTS ---------
+
+// snapshotVersion is bumped when the snapshot layout changes incompatibly.
+const snapshotVersion = 1
+
+// Snapshot is the serialized form of a Discover's scan state.
+type Snapshot struct {
+	Version         int                                `json:"version"`
+	TakenAt         time.Time                          `json:"taken_at"`
+	Results         []PodResult                        `json:"results"`
+	Planets         map[string]PlanetRecord            `json:"planets"`
+	UniversePlanets map[string]map[string]PlanetRecord `json:"universe_planets"`
+	Cubes           map[string]CubeRecord              `json:"cubes"`
+	Labels          map[string]PlanetLabels            `json:"labels,omitempty"`
+	Sessions        []Session                          `json:"sessions,omitempty"`
+	Units           map[string]Unit                    `json:"units,omitempty"`
+}
+
+// snapshotLocked copies the current state. d.mu must be held.
+func (d *Discover) snapshotLocked() Snapshot {
+	s := Snapshot{
+		Version:         snapshotVersion,
+		TakenAt:         time.Now().UTC(),
+		Results:         append([]PodResult(nil), d.Results...),
+		Planets:         make(map[string]PlanetRecord, len(d.Planets)),
+		UniversePlanets: make(map[string]map[string]PlanetRecord, len(d.UniversePlanets)),
+		Cubes:           make(map[string]CubeRecord, len(d.Cubes)),
+	}
+	for k, v := range d.Planets {
+		s.Planets[k] = v
+	}
+	for u, byName := range d.UniversePlanets {
+		m := make(map[string]PlanetRecord, len(byName))
+		for k, v := range byName {
+			m[k] = v
+		}
+		s.UniversePlanets[u] = m
+	}
+	for k, v := range d.Cubes {
+		s.Cubes[k] = v
+	}
+	s.Sessions = append([]Session(nil), d.sessions...)
+	s.Units = d.units.snapshot()
+	if len(d.labels) > 0 {
+		s.Labels = make(map[string]PlanetLabels, len(d.labels))
+		for k, v := range d.labels {
+			s.Labels[k] = v.clone()
+		}
+	}
+	return s
+}
+
+// SaveSnapshot writes Results, Planets, Cubes, planet labels and the unit
+// registry as JSON, so a scan can be persisted and reloaded later without
+// re-scanning.
+func (d *Discover) SaveSnapshot(w io.Writer) error {
+	return d.SaveSnapshotAs(w, SnapshotJSON)
+}
+
+// LoadSnapshot replaces Results, Planets, Cubes, labels and units with a
+// snapshot written by SaveSnapshot or SaveSnapshotAs; the encoding is
+// detected. Derived data for planets that changed is invalidated.
+func (d *Discover) LoadSnapshot(r io.Reader) error {
+	s, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+	return d.restoreSnapshot(s)
+}
+
+func (d *Discover) restoreSnapshot(s Snapshot) error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("snapshot: unsupported version %d", s.Version)
+	}
+	if s.Planets == nil {
+		s.Planets = make(map[string]PlanetRecord)
+	}
+	if s.UniversePlanets == nil {
+		// Older or hand-written snapshots: rebuild from the flat map.
+		s.UniversePlanets = make(map[string]map[string]PlanetRecord)
+		for k, p := range s.Planets {
+			if s.UniversePlanets[p.Universe] == nil {
+				s.UniversePlanets[p.Universe] = make(map[string]PlanetRecord)
+			}
+			s.UniversePlanets[p.Universe][k] = p
+		}
+	}
+	if s.Cubes == nil {
+		s.Cubes = make(map[string]CubeRecord)
+	}
+	d.mu.Lock()
+	d.Results = s.Results
+	d.Planets = s.Planets
+	d.UniversePlanets = s.UniversePlanets
+	d.Cubes = s.Cubes
+	d.labels = s.Labels
+	d.sessions = s.Sessions
+	d.units.restore(s.Units)
+	d.conflicts, d.collided = nil, nil
+	d.mu.Unlock()
+	d.invalidateChanged()
+	return nil
+}
+
+// --- state store ---
+
+// stateKey is where SaveState keeps the current snapshot in the state store.
+const stateKey = "snapshots/latest"
+
+// SaveState writes the current snapshot, unit registry included, to the
+// state store (Config.Store) under "snapshots/latest", encoded with
+// Config.HistoryEncoding, so one backend holds snapshots, history and
+// constellations alike. Unit reservations are in-flight locks and not saved.
+func (d *Discover) SaveState() error {
+	d.mu.Lock()
+	s := d.snapshotLocked()
+	d.mu.Unlock()
+	var buf bytes.Buffer
+	if err := encodeSnapshot(&buf, s, d.Config.HistoryEncoding); err != nil {
+		return err
+	}
+	if err := d.store().Put(stateKey, buf.Bytes()); err != nil {
+		return fmt.Errorf("%s: %w", stateKey, err)
+	}
+	return nil
+}
+
+// LoadState restores the snapshot saved by SaveState, as LoadSnapshot does.
+// Without one the error wraps ErrNotFound.
+func (d *Discover) LoadState() error {
+	data, err := d.store().Get(stateKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", stateKey, err)
+	}
+	s, err := decodeSnapshot(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", stateKey, err)
+	}
+	return d.restoreSnapshot(s)
+}
@@ -0,0 +1,273 @@
+package discover
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// --------- SPATIAL INDEX ---------
+
+// The planet index is a KD-tree over Planets' coordinates, stored as an
+// implicit balanced tree: the node of range [lo, hi) is at (lo+hi)/2, split
+// on axis depth%3. It is rebuilt lazily on the first query after a scan,
+// snapshot load or merge, or when the size of Planets changes. Code that
+// moves planets by editing Planets in place should call RebuildIndex.
+// Planets with non-finite coordinates are left out, as the linear scans
+// never matched them either.
+
+type kdEntry struct {
+	p    [3]float64
+	name string
+}
+
+type kdTree struct {
+	pts []kdEntry
+}
+
+func buildKDTree(pts []kdEntry) *kdTree {
+	t := &kdTree{pts: pts}
+	t.build(0, len(pts), 0)
+	return t
+}
+
+func (t *kdTree) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	axis := depth % 3
+	part := t.pts[lo:hi]
+	sort.Slice(part, func(i, j int) bool { return part[i].p[axis] < part[j].p[axis] })
+	mid := (lo + hi) / 2
+	t.build(lo, mid, depth+1)
+	t.build(mid+1, hi, depth+1)
+}
+
+func dist2(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// nearest returns the index of the closest entry and its squared distance,
+// or -1 for an empty tree.
+func (t *kdTree) nearest(q [3]float64) (int, float64) {
+	best, bestD2 := -1, math.Inf(1)
+	var walk func(lo, hi, depth int)
+	walk = func(lo, hi, depth int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		e := t.pts[mid]
+		if d2 := dist2(q, e.p); d2 < bestD2 {
+			best, bestD2 = mid, d2
+		}
+		diff := q[depth%3] - e.p[depth%3]
+		if diff < 0 {
+			walk(lo, mid, depth+1)
+			if diff*diff < bestD2 {
+				walk(mid+1, hi, depth+1)
+			}
+		} else {
+			walk(mid+1, hi, depth+1)
+			if diff*diff < bestD2 {
+				walk(lo, mid, depth+1)
+			}
+		}
+	}
+	walk(0, len(t.pts), 0)
+	return best, bestD2
+}
+
+// within calls visit for every entry whose squared distance to q is less
+// than r2 (or equal, with inclusive), until visit returns false.
+func (t *kdTree) within(q [3]float64, r2 float64, inclusive bool, visit func(i int, d2 float64) bool) {
+	var walk func(lo, hi, depth int) bool
+	walk = func(lo, hi, depth int) bool {
+		if lo >= hi {
+			return true
+		}
+		mid := (lo + hi) / 2
+		e := t.pts[mid]
+		if d2 := dist2(q, e.p); d2 < r2 || (inclusive && d2 == r2) {
+			if !visit(mid, d2) {
+				return false
+			}
+		}
+		diff := q[depth%3] - e.p[depth%3]
+		if diff < 0 || diff*diff <= r2 {
+			if !walk(lo, mid, depth+1) {
+				return false
+			}
+		}
+		if diff >= 0 || diff*diff <= r2 {
+			return walk(mid+1, hi, depth+1)
+		}
+		return true
+	}
+	walk(0, len(t.pts), 0)
+}
+
+// knn returns the indexes and squared distances of the k nearest entries,
+// nearest first (ties by name).
+func (t *kdTree) knn(q [3]float64, k int) ([]int, []float64) {
+	if k <= 0 {
+		return nil, nil
+	}
+	h := &kdHeap{t: t}
+	var walk func(lo, hi, depth int)
+	walk = func(lo, hi, depth int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		e := t.pts[mid]
+		d2 := dist2(q, e.p)
+		switch {
+		case len(h.idx) < k:
+			heap.Push(h, kdHit{mid, d2})
+		case h.farther(kdHit{h.idx[0].i, h.idx[0].d2}, kdHit{mid, d2}):
+			h.idx[0] = kdHit{mid, d2}
+			heap.Fix(h, 0)
+		}
+		diff := q[depth%3] - e.p[depth%3]
+		near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+		if diff >= 0 {
+			near, far = far, near
+		}
+		walk(near[0], near[1], depth+1)
+		if len(h.idx) < k || diff*diff <= h.idx[0].d2 {
+			walk(far[0], far[1], depth+1)
+		}
+	}
+	walk(0, len(t.pts), 0)
+
+	hits := h.idx
+	sort.Slice(hits, func(i, j int) bool { return h.farther(hits[j], hits[i]) })
+	idx, d2s := make([]int, len(hits)), make([]float64, len(hits))
+	for i, hit := range hits {
+		idx[i], d2s[i] = hit.i, hit.d2
+	}
+	return idx, d2s
+}
+
+type kdHit struct {
+	i  int
+	d2 float64
+}
+
+// kdHeap is a max-heap on distance, so the root is the worst hit kept.
+type kdHeap struct {
+	t   *kdTree
+	idx []kdHit
+}
+
+// farther orders hits by distance, then name.
+func (h *kdHeap) farther(a, b kdHit) bool {
+	if a.d2 != b.d2 {
+		return a.d2 > b.d2
+	}
+	return h.t.pts[a.i].name > h.t.pts[b.i].name
+}
+
+func (h *kdHeap) Len() int           { return len(h.idx) }
+func (h *kdHeap) Less(i, j int) bool { return h.farther(h.idx[i], h.idx[j]) }
+func (h *kdHeap) Swap(i, j int)      { h.idx[i], h.idx[j] = h.idx[j], h.idx[i] }
+func (h *kdHeap) Push(x any)         { h.idx = append(h.idx, x.(kdHit)) }
+func (h *kdHeap) Pop() any {
+	x := h.idx[len(h.idx)-1]
+	h.idx = h.idx[:len(h.idx)-1]
+	return x
+}
+
+type spatialIndex struct {
+	mu    sync.Mutex
+	tree  *kdTree
+	size  int // len(Planets) when built
+	dirty atomic.Bool
+}
+
+// planetIndex returns an up-to-date index. d.mu must not be held.
+func (d *Discover) planetIndex() *kdTree {
+	ix := &d.index
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ix.tree != nil && !ix.dirty.Load() && ix.size == len(d.Planets) {
+		return ix.tree
+	}
+	ix.dirty.Store(false)
+	pts := make([]kdEntry, 0, len(d.Planets))
+	for name, p := range d.Planets {
+		if finite3(p.Coordinates) {
+			pts = append(pts, kdEntry{p: p.Coordinates, name: name})
+		}
+	}
+	ix.tree, ix.size = buildKDTree(pts), len(d.Planets)
+	return ix.tree
+}
+
+func finite3(v [3]float64) bool {
+	for _, c := range v {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// RebuildIndex marks the planet index stale so the next query rebuilds it.
+// Scans, LoadSnapshot and Merge do this automatically.
+func (d *Discover) RebuildIndex() {
+	d.index.dirty.Store(true)
+}
+
+// FindPlanetsWithin returns the names of the planets no further than radius
+// from point, nearest first (ties by name).
+func (d *Discover) FindPlanetsWithin(point []float64, radius float64) []string {
+	defer perfTrack("FindPlanetsWithin")()
+	t := d.planetIndex()
+	type hit struct {
+		name string
+		d2   float64
+	}
+	var hits []hit
+	t.within(vec3f(point), radius*radius, true, func(i int, d2 float64) bool {
+		hits = append(hits, hit{t.pts[i].name, d2})
+		return true
+	})
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].d2 != hits[j].d2 {
+			return hits[i].d2 < hits[j].d2
+		}
+		return hits[i].name < hits[j].name
+	})
+	names := make([]string, len(hits))
+	for i, h := range hits {
+		names[i] = h.name
+	}
+	return names
+}
+
+// PlanetDistance is a planet name and its distance from a query point.
+type PlanetDistance struct {
+	Name     string
+	Distance float64
+}
+
+// FindKNearestPlanets returns the k planets closest to point with their
+// distances, nearest first (ties by name). Fewer are returned if fewer planets
+// are known.
+func (d *Discover) FindKNearestPlanets(point []float64, k int) []PlanetDistance {
+	defer perfTrack("FindKNearestPlanets")()
+	t := d.planetIndex()
+	idx, d2s := t.knn(vec3f(point), k)
+	out := make([]PlanetDistance, len(idx))
+	for i := range idx {
+		out[i] = PlanetDistance{Name: t.pts[idx[i]].name, Distance: math.Sqrt(d2s[i])}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package discover
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// --------- SPAWN PLANS ---------
+
+// SpawnPoint is one proposed spawn position in a SpawnPlan.
+type SpawnPoint struct {
+	Position []float64
+	Rotation float64 // degrees, facing outward (see CalculateRotationOutward)
+	Free     bool    // false if the point falls inside an exclusion zone
+}
+
+// SpawnPlan is a set of proposed spawn positions around a planet, computed
+// from the last scan without touching the live world.
+type SpawnPlan struct {
+	Planet     PlanetRecord
+	Radius     float64
+	MinDist    float64        // exclusion radius around every other planet
+	Exclusions []PlanetRecord // other planets whose exclusion zone reaches the spawn sphere
+	Points     []SpawnPoint
+}
+
+// PlanSpawns proposes n spawn positions on a sphere of the given radius around
+// planetName and marks those closer than minDist to any other planet.
+func (d *Discover) PlanSpawns(planetName string, n int, radius, minDist float64) (*SpawnPlan, error) {
+	positions, err := d.GenerateSpawnPositions(planetName, n, radius)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	planet := d.Planets[planetName]
+	var exclusions []PlanetRecord
+	for name, p := range d.Planets {
+		if name != planetName && distance3(p.Coordinates, planet.Coordinates) < radius+minDist {
+			exclusions = append(exclusions, p)
+		}
+	}
+	d.mu.Unlock()
+
+	plan := &SpawnPlan{Planet: planet, Radius: radius, MinDist: minDist, Exclusions: exclusions}
+	center := planet.Coordinates[:]
+	for _, pos := range positions {
+		free := true
+		for _, ex := range exclusions {
+			if distance3(ex.Coordinates, [3]float64{pos[0], pos[1], pos[2]}) < minDist {
+				free = false
+				break
+			}
+		}
+		plan.Points = append(plan.Points, SpawnPoint{
+			Position: pos,
+			Rotation: CalculateRotationOutward(center, pos),
+			Free:     free,
+		})
+	}
+	return plan, nil
+}
+
+// FreePoints returns the positions of the points outside every exclusion zone.
+func (p *SpawnPlan) FreePoints() [][]float64 {
+	var out [][]float64
+	for _, sp := range p.Points {
+		if sp.Free {
+			out = append(out, sp.Position)
+		}
+	}
+	return out
+}
+
+// RenderPreview writes a top-down (X/Z) SVG of the plan: the target planet and
+// spawn sphere, exclusion zones of nearby planets, and each spawn point with a
+// tick showing its orientation. Free points are green, blocked ones red.
+func (p *SpawnPlan) RenderPreview(w io.Writer) error {
+	const size = 600.0
+	cx, cz := p.Planet.Coordinates[0], p.Planet.Coordinates[2]
+	extent := p.Radius + p.MinDist
+	if extent <= 0 {
+		extent = 1
+	}
+	scale := size / 2 / (extent * 1.1)
+	toSVG := func(x, z float64) (float64, float64) {
+		return size/2 + (x-cx)*scale, size/2 + (z-cz)*scale
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n", size, size, size, size)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#111"/>`+"\n")
+	for _, ex := range p.Exclusions {
+		x, y := toSVG(ex.Coordinates[0], ex.Coordinates[2])
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="#c33" fill-opacity="0.2" stroke="#c33"/>`+"\n", x, y, p.MinDist*scale)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#c33" font-size="12" text-anchor="middle">%s</text>`+"\n", x, y, svgEscape(ex.Name))
+	}
+	fmt.Fprintf(&b, `<circle cx="%g" cy="%g" r="%.1f" fill="none" stroke="#555" stroke-dasharray="4 4"/>`+"\n", size/2, size/2, p.Radius*scale)
+	fmt.Fprintf(&b, `<circle cx="%g" cy="%g" r="6" fill="#48f"/>`+"\n", size/2, size/2)
+	fmt.Fprintf(&b, `<text x="%g" y="%g" fill="#48f" font-size="14" text-anchor="middle">%s</text>`+"\n", size/2, size/2-10, svgEscape(p.Planet.Name))
+	for _, sp := range p.Points {
+		x, y := toSVG(sp.Position[0], sp.Position[2])
+		color := "#3c3"
+		if !sp.Free {
+			color = "#e33"
+		}
+		rad := sp.Rotation * math.Pi / 180
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", x, y, x+math.Cos(rad)*10, y+math.Sin(rad)*10, color)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", x, y, color)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func distance3(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func svgEscape(s string) string { return svgEscaper.Replace(s) }
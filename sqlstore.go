@@ -0,0 +1,160 @@
+package discover
+
+import (
+	"database/sql"
+	"time"
+)
+
+// --------- SCAN HISTORY STORAGE ---------
+
+// ScanRun is one ScanAll: when it ran and everything it found.
+type ScanRun struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Results    []PodResult
+	Planets    []PlanetRecord // as stored in Planets after the scan
+	Cubes      []CubeRecord
+}
+
+// ScanStorage records scan runs. Set Config.History and every ScanAll is
+// saved after it completes; a failed save is reported as a WarnStorage
+// warning. SQLStorage ships with the package and other backends only need
+// this one method.
+type ScanStorage interface {
+	SaveRun(run ScanRun) error
+}
+
+// SQLStorage writes scan runs to a SQL database. It uses portable SQL with
+// "?" placeholders and is meant for SQLite; bring your own driver:
+//
+//	import _ "modernc.org/sqlite" // or github.com/mattn/go-sqlite3
+//
+//	db, _ := sql.Open("sqlite", "discovery.db")
+//	store, err := discover.NewSQLStorage(db)
+//	cfg.History = store
+//
+// Tables: scan_runs, pod_results, planets and cubes; every row carries the
+// run_id of its scan, and timestamps are RFC 3339 UTC text.
+type SQLStorage struct {
+	db *sql.DB
+}
+
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS scan_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at TEXT NOT NULL,
+		finished_at TEXT NOT NULL,
+		pods INTEGER NOT NULL,
+		succeeded INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS pod_results (
+		run_id INTEGER NOT NULL REFERENCES scan_runs(id),
+		host TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		success INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		cubes INTEGER NOT NULL,
+		planets INTEGER NOT NULL,
+		warnings INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS planets (
+		run_id INTEGER NOT NULL REFERENCES scan_runs(id),
+		name TEXT NOT NULL,
+		universe TEXT NOT NULL,
+		x REAL NOT NULL,
+		y REAL NOT NULL,
+		z REAL NOT NULL,
+		seed INTEGER NOT NULL,
+		biome_type INTEGER NOT NULL,
+		host TEXT NOT NULL,
+		port INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS cubes (
+		run_id INTEGER NOT NULL REFERENCES scan_runs(id),
+		name TEXT NOT NULL,
+		host TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		state TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS planets_name ON planets(name, run_id)`,
+}
+
+// NewSQLStorage creates the tables if needed and returns the storage.
+func NewSQLStorage(db *sql.DB) (*SQLStorage, error) {
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &SQLStorage{db: db}, nil
+}
+
+// DB returns the underlying database, for queries over the history.
+func (s *SQLStorage) DB() *sql.DB { return s.db }
+
+// SaveRun writes one run in a single transaction.
+func (s *SQLStorage) SaveRun(run ScanRun) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	succeeded := 0
+	for _, r := range run.Results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	res, err := tx.Exec(`INSERT INTO scan_runs (started_at, finished_at, pods, succeeded) VALUES (?, ?, ?, ?)`,
+		sqlTime(run.StartedAt), sqlTime(run.FinishedAt), len(run.Results), succeeded)
+	if err != nil {
+		return err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, r := range run.Results {
+		if _, err := tx.Exec(`INSERT INTO pod_results (run_id, host, port, success, error, cubes, planets, warnings) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, r.Host, r.Port, r.Success, r.Error, len(r.Cubes), len(r.Planets), len(r.Warnings)); err != nil {
+			return err
+		}
+	}
+	for _, p := range run.Planets {
+		if _, err := tx.Exec(`INSERT INTO planets (run_id, name, universe, x, y, z, seed, biome_type, host, port) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, p.Name, p.Universe, p.Coordinates[0], p.Coordinates[1], p.Coordinates[2], p.Seed, p.BiomeType, p.Host, p.Port); err != nil {
+			return err
+		}
+	}
+	for _, c := range run.Cubes {
+		var state any
+		if c.State != nil {
+			state = string(c.State)
+		}
+		if _, err := tx.Exec(`INSERT INTO cubes (run_id, name, host, port, state) VALUES (?, ?, ?, ?, ?)`,
+			runID, c.Name, c.Host, c.Port, state); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func sqlTime(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+
+// recordRun saves the scan that just finished to Config.History.
+func (d *Discover) recordRun(started time.Time, results []PodResult) {
+	if d.Config.History == nil {
+		return
+	}
+	run := ScanRun{StartedAt: started, FinishedAt: time.Now(), Results: results, Planets: d.PlanetList()}
+	for _, name := range d.CubeNames() {
+		c, _ := d.LookupCube(name)
+		run.Cubes = append(run.Cubes, c)
+	}
+	if err := d.Config.History.SaveRun(run); err != nil {
+		d.mu.Lock()
+		d.emitWarningsLocked(PodResult{Warnings: []Warning{{Kind: WarnStorage, Command: "save_run", Message: err.Error()}}})
+		d.mu.Unlock()
+	}
+}
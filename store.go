@@ -0,0 +1,179 @@
+package discover
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --------- STATE STORE ---------
+
+// StateStore is the persistence backend for SaveState/LoadState (the
+// snapshot, unit registry included), history and constellations. Keys are
+// slash-separated paths such as "snapshots/latest". FileStore, KVStore and
+// MemoryStore ship with the package; Redis, S3 and similar backends are not
+// included, but only need these four methods around their client.
+type StateStore interface {
+	Get(key string) ([]byte, error) // ErrNotFound if key is absent
+	Put(key string, data []byte) error
+	Delete(key string) error // deleting a missing key is not an error
+	List(prefix string) ([]string, error)
+}
+
+// ErrNotFound is returned by StateStore.Get for missing keys.
+var ErrNotFound = errors.New("key not found")
+
+// store returns Config.Store, falling back to a memory store of d's own,
+// created on first use, so instances without a Store never share state.
+func (d *Discover) store() StateStore {
+	if d.Config.Store != nil {
+		return d.Config.Store
+	}
+	d.memOnce.Do(func() { d.mem = NewMemoryStore() })
+	return d.mem
+}
+
+// --- memory ---
+
+// MemoryStore keeps state in memory; each Discover gets its own when
+// Config.Store is nil.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (m *MemoryStore) Put(key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *MemoryStore) List(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// --- file ---
+
+// FileStore keeps one file per key under Dir. Key segments are path-escaped,
+// so any key is safe to use. Writes go through a temp file and rename.
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (f *FileStore) path(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+		if parts[i] == "." || parts[i] == ".." {
+			parts[i] = strings.ReplaceAll(parts[i], ".", "%2E")
+		}
+	}
+	return filepath.Join(append([]string{f.Dir}, parts...)...)
+}
+
+func (f *FileStore) Get(key string) ([]byte, error) {
+	b, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+func (f *FileStore) Put(key string, data []byte) error {
+	p := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (f *FileStore) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (f *FileStore) List(prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(f.Dir, func(p string, de os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() || strings.HasPrefix(de.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(f.Dir, p)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		for i, part := range parts {
+			if parts[i], err = url.PathUnescape(part); err != nil {
+				return nil // not written by FileStore
+			}
+		}
+		if key := strings.Join(parts, "/"); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
@@ -0,0 +1,217 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// --- Streaming replies ---
+//
+// Large replies (get_planets on big worlds) are decoded straight off the wire
+// with a json.Decoder instead of being read into a string first. Transports
+// that can expose one message as an io.Reader implement messageStreamer; the
+// rest fall back to readMsg.
+
+// messageStreamer yields the next message as a reader. The reader reports
+// io.EOF at the end of the message and must be drained before the next call.
+type messageStreamer interface {
+	nextMessage() (io.Reader, error)
+}
+
+func openMessage(t msgTransport) (io.Reader, error) {
+	if ms, ok := t.(messageStreamer); ok {
+		return ms.nextMessage()
+	}
+	msg, err := t.readMsg()
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(msg), nil
+}
+
+func (c *podConn) nextMessage() (io.Reader, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	if c.framing == FramingLengthPrefix {
+		var header [4]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(header[:])
+		if n > maxFrameSize {
+			return nil, errFrameTooLarge
+		}
+		return io.LimitReader(c.reader, int64(n)), nil
+	}
+	return &delimReader{br: c.reader, delim: []byte(c.delim)}, nil
+}
+
+func (g *gzipTransport) nextMessage() (io.Reader, error) {
+	r, err := openMessage(g.inner)
+	if err != nil {
+		return nil, err
+	}
+	if g.base64 {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipMessage{zr: zr, src: r}, nil
+}
+
+// gzipMessage drains the framed source after the gzip stream ends so the
+// trailing delimiter is consumed.
+type gzipMessage struct {
+	zr  *gzip.Reader
+	src io.Reader
+}
+
+func (m *gzipMessage) Read(p []byte) (int, error) {
+	n, err := m.zr.Read(p)
+	if err == io.EOF {
+		io.Copy(io.Discard, m.src)
+	}
+	return n, err
+}
+
+// delimReader reads one delimiter-terminated message from br, stopping before
+// the delimiter. Up to len(delim)-1 bytes are held back while they might be
+// the start of the delimiter.
+type delimReader struct {
+	br    *bufio.Reader
+	delim []byte
+	carry []byte // held-back bytes that may begin the delimiter
+	out   []byte // ready to return
+	err   error  // returned once out is drained
+}
+
+func (r *delimReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 && r.err == nil {
+		r.fill()
+	}
+	if len(r.out) > 0 {
+		n := copy(p, r.out)
+		r.out = r.out[n:]
+		return n, nil
+	}
+	return 0, r.err
+}
+
+func (r *delimReader) fill() {
+	if len(r.delim) == 0 {
+		r.err = io.ErrUnexpectedEOF
+		return
+	}
+	chunk, err := r.br.ReadSlice(r.delim[len(r.delim)-1])
+	data := append(r.carry, chunk...)
+	r.carry = nil
+	if bytes.HasSuffix(data, r.delim) {
+		r.out = data[:len(data)-len(r.delim)]
+		r.err = io.EOF
+		return
+	}
+	if err != nil && err != bufio.ErrBufferFull {
+		r.out = data
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+		return
+	}
+	keep := min(len(r.delim)-1, len(data))
+	r.out = data[:len(data)-keep]
+	r.carry = append([]byte(nil), data[len(data)-keep:]...)
+}
+
+// callCommandStream is callCommand for a transport held exclusively by the
+// caller: the reply is decoded while it is read.
+func callCommandStream(t msgTransport, cmdType string, req, resp any) error {
+	if err := sendTypedRequest(t, cmdType, req, resp); err != nil {
+		return err
+	}
+	return readTypedReply(t, cmdType, resp)
+}
+
+// sendTypedRequest and readTypedReply are the two halves of callCommandStream,
+// split so pipelined scans can send several requests before reading.
+func sendTypedRequest(t msgTransport, cmdType string, req, resp any) error {
+	if p, ok := t.(*protoTransport); ok {
+		return sendProtoRequest(p, cmdType, req, resp)
+	}
+	msg, err := encodeTypedRequest(cmdType, req, resp)
+	if err != nil {
+		return err
+	}
+	if err := t.sendMsg(msg); err != nil {
+		return &CommandError{Type: cmdType, Stage: "send", Err: err}
+	}
+	return nil
+}
+
+func readTypedReply(t msgTransport, cmdType string, resp any) error {
+	if p, ok := t.(*protoTransport); ok {
+		return readProtoReply(p, cmdType, resp)
+	}
+	r, err := openMessage(t)
+	if err != nil {
+		return &CommandError{Type: cmdType, Stage: "read", Err: err}
+	}
+	return decodeTypedStream(cmdType, r, resp)
+}
+
+func decodeTypedStream(cmdType string, r io.Reader, resp any) error {
+	err := json.NewDecoder(r).Decode(resp)
+	io.Copy(io.Discard, r) // consume the rest of the message
+	if err != nil {
+		return &CommandError{Type: cmdType, Stage: "decode", Err: err}
+	}
+	if v, ok := resp.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return &CommandError{Type: cmdType, Stage: "validate", Err: err}
+		}
+	}
+	return nil
+}
+
+// withConn runs fn on the client's connection under the client lock, for
+// exchanges that stream instead of returning a string.
+func (c *PodClient) withConn(ctx context.Context, fn func(t msgTransport) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.conn == nil {
+		if err := c.connectLocked(ctx); err != nil {
+			c.markSeen(false)
+			return err
+		}
+	}
+	c.lastUsed = time.Now()
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err := fn(conn)
+	var cerr *CommandError
+	transportFailed := err != nil && (!errors.As(err, &cerr) || cerr.Stage == "send" || cerr.Stage == "read" || cerr.Stage == "decode")
+	c.markSeen(!transportFailed)
+	if transportFailed {
+		// A half-read message leaves the stream out of sync; start over.
+		c.dropLocked()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
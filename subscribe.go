@@ -0,0 +1,183 @@
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// --------- STATE SUBSCRIPTIONS ---------
+
+// Pods that support it push state changes on a connection after a subscribe
+// command. The subscription uses a connection of its own, so the client's
+// request/reply connection is unaffected.
+//
+//	-> {"type":"subscribe","topics":["cubes","planets"]}
+//	<- {"ok":true}
+//	<- {"type":"cube_state","cube_name":"...","position":{...},...}
+//	<- {"type":"planet_event","planet_name":"...",...}
+const CmdSubscribe = "subscribe"
+
+// Update types pods push, and the topics that select them.
+const (
+	UpdateCubeState   = "cube_state"
+	UpdatePlanetEvent = "planet_event"
+
+	TopicCubes   = "cubes"
+	TopicPlanets = "planets"
+)
+
+// ErrSubscribeUnsupported is returned by Subscribe when the pod answers the
+// subscribe command with anything but an ack.
+var ErrSubscribeUnsupported = errors.New("pod does not support subscriptions")
+
+// SubscribeOptions tunes PodClient.Subscribe.
+type SubscribeOptions struct {
+	Topics      []string      // nil = every topic the pod offers
+	Buffer      int           // channel capacity; 0 = 64
+	IdleTimeout time.Duration // reconnect after this long without an update; 0 = never
+	RetryMin    time.Duration // first reconnect delay, doubled per failure; 0 = 500ms
+	RetryMax    time.Duration // cap on the reconnect delay; 0 = 30s
+}
+
+// StateUpdate is one message pushed by a subscribed pod, or a notice that the
+// subscription dropped (Err set, nothing else). Updates after a drop have
+// Resumed set on the first one, as changes in between were missed.
+type StateUpdate struct {
+	Pod      PodRef
+	Type     string          // UpdateCubeState, UpdatePlanetEvent, ...
+	Cube     *CubeState      // decoded for cube_state updates
+	Raw      json.RawMessage // the whole message
+	Received time.Time
+	Resumed  bool
+	Err      error
+}
+
+type subscribeRequest struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// Subscribe opens a subscription and returns the channel updates arrive on.
+// The first subscribe is done before returning, so an unsupporting or
+// unreachable pod is reported here. After that, a dropped connection is sent
+// as an update with Err and redialed with backoff until ctx is cancelled,
+// when the channel is closed. A slow reader stalls the stream, not the pod.
+func (c *PodClient) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan StateUpdate, error) {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	if opts.RetryMin <= 0 {
+		opts.RetryMin = 500 * time.Millisecond
+	}
+	if opts.RetryMax <= 0 {
+		opts.RetryMax = 30 * time.Second
+	}
+	conn, err := c.subscribe(opts)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan StateUpdate, opts.Buffer)
+	go c.stream(ctx, conn, opts, ch)
+	return ch, nil
+}
+
+// subscribe dials a fresh connection and sends the subscribe command.
+func (c *PodClient) subscribe(opts SubscribeOptions) (msgTransport, error) {
+	conn, err := dialPod(c.cfg, c.PodRef)
+	if err != nil {
+		return nil, err
+	}
+	msg, _ := json.Marshal(subscribeRequest{Type: CmdSubscribe, Topics: opts.Topics})
+	if err := conn.sendMsg(string(msg)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := conn.readMsg()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var ack AckResponse
+	if json.Unmarshal([]byte(resp), &ack) != nil || !ack.OK {
+		conn.Close()
+		if ack.Error != "" {
+			return nil, fmt.Errorf("subscribe: pod refused: %s", ack.Error)
+		}
+		return nil, ErrSubscribeUnsupported
+	}
+	idle := opts.IdleTimeout
+	if idle <= 0 {
+		idle = foreverTimeout
+	}
+	conn.setTimeout(idle)
+	return conn, nil
+}
+
+func (c *PodClient) stream(ctx context.Context, conn msgTransport, opts SubscribeOptions, ch chan<- StateUpdate) {
+	defer close(ch)
+	send := func(u StateUpdate) bool {
+		u.Pod = c.PodRef
+		select {
+		case ch <- u:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	resumed := false
+	for {
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		err := readUpdates(conn, func(u StateUpdate) bool {
+			u.Resumed, resumed = resumed, false
+			return send(u)
+		})
+		stop()
+		conn.Close()
+		if ctx.Err() != nil || !send(StateUpdate{Received: time.Now(), Err: err}) {
+			return
+		}
+
+		resumed = true
+		for delay := opts.RetryMin; ; delay = min(2*delay, opts.RetryMax) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if conn, err = c.subscribe(opts); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// readUpdates decodes pushed messages until the connection fails or deliver
+// returns false.
+func readUpdates(conn msgTransport, deliver func(StateUpdate) bool) error {
+	for {
+		msg, err := conn.readMsg()
+		if err != nil {
+			return err
+		}
+		u := StateUpdate{Raw: json.RawMessage(msg), Received: time.Now()}
+		var head struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(u.Raw, &head) != nil {
+			continue // not JSON; nothing to deliver
+		}
+		u.Type = head.Type
+		if u.Type == UpdateCubeState {
+			var s CubeState
+			if json.Unmarshal(u.Raw, &s) == nil {
+				u.Cube = &s
+			}
+		}
+		if !deliver(u) {
+			return nil
+		}
+	}
+}
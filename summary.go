@@ -0,0 +1,112 @@
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// --------- SCAN SUMMARY ---------
+
+// SummaryFormat selects WriteSummary's output.
+type SummaryFormat int
+
+const (
+	SummaryText SummaryFormat = iota // the PrintSummary layout
+	SummaryJSON                      // ScanSummary as indented JSON
+)
+
+// PodSummary is one result row of a ScanSummary.
+type PodSummary struct {
+	PodRef
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Cubes    int    `json:"cubes"`
+	Planets  int    `json:"planets"`
+	Warnings int    `json:"warnings"`
+}
+
+// ScanSummary holds the numbers PrintSummary shows.
+type ScanSummary struct {
+	Pods          []PodSummary     `json:"pods"` // one per result, quarantined pods excluded
+	Quarantined   []QuarantinedPod `json:"quarantined,omitempty"`
+	Configured    int              `json:"configured"` // pods the config (and probe) expect
+	Successful    int              `json:"successful"`
+	TotalCubes    int              `json:"total_cubes"`   // summed over successful results
+	TotalPlanets  int              `json:"total_planets"` // summed over successful results
+	UniquePlanets int              `json:"unique_planets"`
+	Conflicts     int              `json:"conflicts"` // planet names reported by several pods
+}
+
+// Summary computes the scan summary.
+func (d *Discover) Summary() ScanSummary {
+	s := ScanSummary{Configured: len(d.podAddrs())}
+	d.mu.Lock()
+	for _, res := range d.Results {
+		if res.Error == errQuarantined.Error() {
+			continue // listed in Quarantined
+		}
+		ps := PodSummary{PodRef: res.PodRef, Success: res.Success, Error: res.Error, Warnings: len(res.Warnings)}
+		if res.Success {
+			ps.Cubes, ps.Planets = len(res.Cubes), len(res.Planets)
+			s.Successful++
+			s.TotalCubes += ps.Cubes
+			s.TotalPlanets += ps.Planets
+		}
+		s.Pods = append(s.Pods, ps)
+	}
+	s.UniquePlanets = len(d.Planets)
+	d.mu.Unlock()
+	s.Quarantined = d.Quarantined()
+	s.Conflicts = len(d.Conflicts())
+	return s
+}
+
+// WriteSummary writes the scan summary to w as text or JSON.
+func (d *Discover) WriteSummary(w io.Writer, format SummaryFormat) error {
+	s := d.Summary()
+	if format == SummaryJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	ew := &errWriter{w: w}
+	ew.printf("\n=== D.I.S.C.O.V.E.R.™ SUMMARY ===\n")
+	for _, p := range s.Pods {
+		if p.Success {
+			ew.printf("[%s] ✅ Cubes=%d Planets=%d\n", p.PodRef, p.Cubes, p.Planets)
+		} else {
+			ew.printf("[%s] ❌ %s\n", p.PodRef, p.Error)
+		}
+	}
+	for _, q := range s.Quarantined {
+		ew.printf("[%s] ⛔ Quarantined after %d auth failures\n", q.PodRef, q.Failures)
+	}
+	ew.printf("\nSuccessful pods: %d / %d\n", s.Successful, s.Configured)
+	ew.printf("Total Cubes: %d\n", s.TotalCubes)
+	ew.printf("Total Planets: %d\n", s.TotalPlanets)
+	ew.printf("Unique Planets: %d\n", s.UniquePlanets)
+	if s.Conflicts > 0 {
+		ew.printf("Planet name conflicts: %d\n", s.Conflicts)
+	}
+	return ew.err
+}
+
+// errWriter keeps the first write error so text output can be written
+// without checking every line.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// PrintSummary writes the text summary to stdout.
+func (d *Discover) PrintSummary() {
+	d.WriteSummary(os.Stdout, SummaryText)
+}
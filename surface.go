@@ -0,0 +1,73 @@
+package discover
+
+import "fmt"
+
+// --------- PLANET SURFACES ---------
+
+// RadiusFunc gives a planet's surface radius; values <= 0 mean unknown.
+type RadiusFunc func(PlanetRecord) float64
+
+// SeedRadius returns a RadiusFunc mapping each planet's Seed to a stable
+// radius in [min, max), for worlds whose planet size follows from the seed but
+// whose pods don't report it.
+func SeedRadius(min, max float64) RadiusFunc {
+	return func(p PlanetRecord) float64 {
+		x := uint64(p.Seed) + 0x9e3779b97f4a7c15 // splitmix64 finalizer
+		x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+		x = (x ^ x>>27) * 0x94d049bb133111eb
+		x ^= x >> 31
+		return min + (max-min)*float64(x>>11)/(1<<53)
+	}
+}
+
+// SetPlanetRadius records a planet's radius, e.g. one queried from its pod; it
+// wins over Config.PlanetRadius. A radius <= 0 clears it.
+func (d *Discover) SetPlanetRadius(name string, radius float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.Planets[name]; !ok {
+		return fmt.Errorf("planet %s not found", name)
+	}
+	if radius <= 0 {
+		delete(d.radii, name)
+		return nil
+	}
+	if d.radii == nil {
+		d.radii = make(map[string]float64)
+	}
+	d.radii[name] = radius
+	return nil
+}
+
+// PlanetRadius returns a planet's surface radius: the one set by
+// SetPlanetRadius, else Config.PlanetRadius, else Config.DefaultPlanetRadius.
+func (d *Discover) PlanetRadius(name string) (float64, error) {
+	d.mu.Lock()
+	planet, ok := d.Planets[name]
+	r := d.radii[name]
+	d.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("planet %s not found", name)
+	}
+	if r <= 0 && d.Config.PlanetRadius != nil {
+		r = d.Config.PlanetRadius(planet)
+	}
+	if r <= 0 {
+		r = d.Config.DefaultPlanetRadius
+	}
+	if r <= 0 {
+		return 0, fmt.Errorf("no radius for planet %s", name)
+	}
+	return r, nil
+}
+
+// GenerateSurfaceSpawns places n Fibonacci-spaced spawn points heightOffset
+// above a planet's surface (see PlanetRadius). Cached like
+// GenerateSpawnPositions.
+func (d *Discover) GenerateSurfaceSpawns(planetName string, n int, heightOffset float64) ([][]float64, error) {
+	r, err := d.PlanetRadius(planetName)
+	if err != nil {
+		return nil, err
+	}
+	return d.GenerateSpawnPositions(planetName, n, r+heightOffset)
+}
@@ -0,0 +1,92 @@
+package discover
+
+import "sort"
+
+// --------- PLANET SYSTEMS ---------
+
+// Systems groups planets into systems with DBSCAN over Config.SystemRadius and
+// Config.SystemMinPlanets. With SystemMinPlanets <= 1 this is single-linkage
+// clustering: planets within SystemRadius of each other, directly or through a
+// chain, share a system. Members are sorted by their Planets key (the name,
+// or NamespacedName under CollideNamespace) and each system is keyed by its
+// first member's. Planets with non-finite coordinates are left out.
+func (d *Discover) Systems() map[string][]PlanetRecord {
+	return d.ClusterSystems(d.Config.SystemRadius, d.Config.SystemMinPlanets)
+}
+
+// ClusterSystems is Systems with explicit parameters: a planet with at least
+// minPlanets planets (itself included) within radius is a core planet; core
+// planets within radius of each other share a system, and other planets
+// within radius of a core join its system. The rest are left out.
+func (d *Discover) ClusterSystems(radius float64, minPlanets int) map[string][]PlanetRecord {
+	defer perfTrack("ClusterSystems")()
+	t := d.planetIndex()
+	order := make([]int, len(t.pts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return t.pts[order[a]].name < t.pts[order[b]].name })
+
+	neighbors := func(i int) []int {
+		var out []int
+		t.within(t.pts[i].p, radius*radius, true, func(j int, _ float64) bool {
+			out = append(out, j)
+			return true
+		})
+		return out
+	}
+	const unvisited, noise = 0, -1
+	label := make([]int, len(t.pts)) // cluster number, from 1
+	clusters := 0
+	for _, i := range order {
+		if label[i] != unvisited {
+			continue
+		}
+		seeds := neighbors(i)
+		if len(seeds) < minPlanets {
+			label[i] = noise
+			continue
+		}
+		clusters++
+		label[i] = clusters
+		for len(seeds) > 0 {
+			j := seeds[0]
+			seeds = seeds[1:]
+			switch label[j] {
+			case noise:
+				label[j] = clusters // border planet
+			case unvisited:
+				label[j] = clusters
+				if more := neighbors(j); len(more) >= minPlanets {
+					seeds = append(seeds, more...)
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]string, clusters)
+	for _, i := range order {
+		if label[i] > 0 {
+			members[label[i]] = append(members[label[i]], t.pts[i].name)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string][]PlanetRecord, len(members))
+	for _, names := range members {
+		var sys []PlanetRecord
+		key := ""
+		for _, name := range names {
+			if p, ok := d.Planets[name]; ok {
+				if sys == nil {
+					key = name
+				}
+				sys = append(sys, p)
+			}
+		}
+		if len(sys) > 0 {
+			out[key] = sys // the Planets key: unique, unlike Name under CollideNamespace
+		}
+	}
+	return out
+}
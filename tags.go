@@ -0,0 +1,137 @@
+package discover
+
+import (
+	"fmt"
+	"sort"
+)
+
+// --------- PLANET TAGS ---------
+
+// PlanetLabels are user-attached tags ("home-base", "resource-rich") and
+// key/value annotations for a planet. They are keyed by planet name, survive
+// rescans, and are saved in snapshots.
+type PlanetLabels struct {
+	Tags        []string          `json:"tags,omitempty"` // sorted, unique
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+func (l PlanetLabels) empty() bool { return len(l.Tags) == 0 && len(l.Annotations) == 0 }
+
+func (l PlanetLabels) clone() PlanetLabels {
+	out := PlanetLabels{Tags: append([]string(nil), l.Tags...)}
+	if l.Annotations != nil {
+		out.Annotations = make(map[string]string, len(l.Annotations))
+		for k, v := range l.Annotations {
+			out.Annotations[k] = v
+		}
+	}
+	return out
+}
+
+// TagPlanet adds tags to a known planet.
+func (d *Discover) TagPlanet(name string, tags ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.Planets[name]; !ok {
+		return fmt.Errorf("planet %s not found", name)
+	}
+	l := d.labels[name]
+	l.Tags = uniqueSorted(append(l.Tags, tags...))
+	d.setLabelsLocked(name, l)
+	return nil
+}
+
+// UntagPlanet removes tags from a planet; missing tags are ignored.
+func (d *Discover) UntagPlanet(name string, tags ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l := d.labels[name]
+	drop := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		drop[t] = true
+	}
+	kept := l.Tags[:0:0]
+	for _, t := range l.Tags {
+		if !drop[t] {
+			kept = append(kept, t)
+		}
+	}
+	l.Tags = kept
+	d.setLabelsLocked(name, l)
+}
+
+// AnnotatePlanet sets a key/value annotation on a known planet. An empty
+// value removes the key.
+func (d *Discover) AnnotatePlanet(name, key, value string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.Planets[name]; !ok && value != "" {
+		return fmt.Errorf("planet %s not found", name)
+	}
+	l := d.labels[name].clone()
+	if value == "" {
+		delete(l.Annotations, key)
+	} else {
+		if l.Annotations == nil {
+			l.Annotations = make(map[string]string)
+		}
+		l.Annotations[key] = value
+	}
+	d.setLabelsLocked(name, l)
+	return nil
+}
+
+// setLabelsLocked stores l, dropping empty entries. d.mu must be held.
+func (d *Discover) setLabelsLocked(name string, l PlanetLabels) {
+	if l.empty() {
+		delete(d.labels, name)
+		return
+	}
+	if d.labels == nil {
+		d.labels = make(map[string]PlanetLabels)
+	}
+	d.labels[name] = l
+}
+
+// Labels returns a copy of a planet's tags and annotations.
+func (d *Discover) Labels(name string) PlanetLabels {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.labels[name].clone()
+}
+
+// PlanetTags returns a planet's tags, sorted.
+func (d *Discover) PlanetTags(name string) []string {
+	return d.Labels(name).Tags
+}
+
+// TaggedPlanets returns the names carrying tag, sorted.
+func (d *Discover) TaggedPlanets(tag string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var names []string
+	for name, l := range d.labels {
+		if i := sort.SearchStrings(l.Tags, tag); i < len(l.Tags) && l.Tags[i] == tag {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tag keeps planets carrying tag.
+func (q *Query) Tag(tag string) *Query {
+	return q.Where(func(p PlanetRecord) bool {
+		tags := q.d.PlanetTags(p.Name)
+		i := sort.SearchStrings(tags, tag)
+		return i < len(tags) && tags[i] == tag
+	})
+}
+
+// Annotation keeps planets whose annotation key equals value.
+func (q *Query) Annotation(key, value string) *Query {
+	return q.Where(func(p PlanetRecord) bool {
+		v, ok := q.d.Labels(p.Name).Annotations[key]
+		return ok && v == value
+	})
+}
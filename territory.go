@@ -0,0 +1,103 @@
+package discover
+
+// --------- TERRITORIES ---------
+
+// TerritoryOwner returns the planet that owns point in the nearest-planet
+// (Voronoi) partition of space, or "" if there are no planets.
+func (d *Discover) TerritoryOwner(point []float64) string {
+	t := d.planetIndex()
+	i, _ := t.nearest(vec3f(point))
+	if i < 0 {
+		return ""
+	}
+	return t.pts[i].name
+}
+
+// TerritoryGrid is the box sampled by Territories. An axis with one cell is
+// sampled at its middle, so Cells = {n, 1, n} maps the XZ plane.
+type TerritoryGrid struct {
+	Min, Max [3]float64
+	Cells    [3]int // samples per axis; values < 1 count as 1
+}
+
+// Territory is the nearest-planet owner of every cell of a TerritoryGrid.
+type Territory struct {
+	Grid   TerritoryGrid
+	Owners []string // cell (i, j, k) is at (k*Cells[1]+j)*Cells[0]+i; "" with no planets
+}
+
+// Territories samples the Voronoi partition on g, for assigning territory
+// control per planet. Boundaries and areas come from the result's methods.
+func (d *Discover) Territories(g TerritoryGrid) *Territory {
+	defer perfTrack("Territories")()
+	for a := range g.Cells {
+		g.Cells[a] = max(g.Cells[a], 1)
+	}
+	tree := d.planetIndex()
+	t := &Territory{Grid: g, Owners: make([]string, g.Cells[0]*g.Cells[1]*g.Cells[2])}
+	for k := 0; k < g.Cells[2]; k++ {
+		for j := 0; j < g.Cells[1]; j++ {
+			for i := 0; i < g.Cells[0]; i++ {
+				if n, _ := tree.nearest(vec3f(t.CellCenter(i, j, k))); n >= 0 {
+					t.Owners[t.index(i, j, k)] = tree.pts[n].name
+				}
+			}
+		}
+	}
+	return t
+}
+
+func (t *Territory) index(i, j, k int) int {
+	return (k*t.Grid.Cells[1]+j)*t.Grid.Cells[0] + i
+}
+
+// Owner returns the planet owning cell (i, j, k).
+func (t *Territory) Owner(i, j, k int) string {
+	return t.Owners[t.index(i, j, k)]
+}
+
+// CellCenter returns the world position sampled for cell (i, j, k).
+func (t *Territory) CellCenter(i, j, k int) []float64 {
+	g := t.Grid
+	c := make([]float64, 3)
+	for a, n := range [3]int{i, j, k} {
+		c[a] = g.Min[a] + (float64(n)+0.5)*(g.Max[a]-g.Min[a])/float64(g.Cells[a])
+	}
+	return c
+}
+
+// Cells returns how many cells each planet owns, a proxy for territory size.
+func (t *Territory) Cells() map[string]int {
+	out := make(map[string]int)
+	for _, o := range t.Owners {
+		if o != "" {
+			out[o]++
+		}
+	}
+	return out
+}
+
+// Boundary returns the centers of cells bordering a cell with another owner
+// (along any axis), an approximation of the territory borders.
+func (t *Territory) Boundary() [][]float64 {
+	c := t.Grid.Cells
+	var out [][]float64
+	for k := 0; k < c[2]; k++ {
+		for j := 0; j < c[1]; j++ {
+			for i := 0; i < c[0]; i++ {
+				own := t.Owner(i, j, k)
+				edge := false
+				for _, n := range [][3]int{{i - 1, j, k}, {i + 1, j, k}, {i, j - 1, k}, {i, j + 1, k}, {i, j, k - 1}, {i, j, k + 1}} {
+					if n[0] >= 0 && n[1] >= 0 && n[2] >= 0 && n[0] < c[0] && n[1] < c[1] && n[2] < c[2] && t.Owner(n[0], n[1], n[2]) != own {
+						edge = true
+						break
+					}
+				}
+				if edge {
+					out = append(out, t.CellCenter(i, j, k))
+				}
+			}
+		}
+	}
+	return out
+}
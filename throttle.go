@@ -0,0 +1,85 @@
+package discover
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// --- Bandwidth throttling ---
+
+// throttledConn rate-limits reads and writes on one connection with a token
+// bucket per direction. Config.ReadRate/WriteRate are bytes per second; the
+// bucket holds one second's worth, so short bursts pass at full speed.
+type throttledConn struct {
+	net.Conn
+	r, w *rateLimiter
+}
+
+// throttle wraps conn if any rate is configured.
+func throttle(cfg Config, conn net.Conn) net.Conn {
+	if cfg.ReadRate <= 0 && cfg.WriteRate <= 0 {
+		return conn
+	}
+	return &throttledConn{Conn: conn, r: newRateLimiter(cfg.ReadRate), w: newRateLimiter(cfg.WriteRate)}
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if c.r == nil {
+		return c.Conn.Read(p)
+	}
+	if len(p) > c.r.burst {
+		p = p[:c.r.burst]
+	}
+	n, err := c.Conn.Read(p)
+	c.r.take(n)
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	if c.w == nil {
+		return c.Conn.Write(p)
+	}
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), c.w.burst)]
+		c.w.take(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSec int) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(bytesPerSec), burst: bytesPerSec, tokens: float64(bytesPerSec), last: time.Now()}
+}
+
+// take spends n bytes, sleeping until the bucket is no longer in debt.
+func (l *rateLimiter) take(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, float64(l.burst))
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
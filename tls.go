@@ -0,0 +1,44 @@
+package discover
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// --- TLS ---
+
+// tlsConfigFor returns the TLS settings for one target. Config.TLS is cloned
+// so per-target names never leak between dials. The server name (SNI and the
+// name the certificate must match) comes from Config.ServerNames, keyed by
+// "host:port", the host or URL as configured, or the dialed hostname; it
+// defaults to Config.TLS.ServerName and then the dialed hostname, so pods can
+// be scanned by IP while validating a certificate issued for a DNS name.
+func (cfg Config) tlsConfigFor(pod PodRef, dialHost string) *tls.Config {
+	tc := &tls.Config{}
+	if cfg.TLS != nil {
+		tc = cfg.TLS.Clone()
+	}
+	for _, key := range []string{pod.String(), pod.Host, dialHost} {
+		if name, ok := cfg.ServerNames[key]; ok {
+			tc.ServerName = name
+			return tc
+		}
+	}
+	if tc.ServerName == "" {
+		tc.ServerName = dialHost
+	}
+	return tc
+}
+
+// tlsHandshake wraps conn in a TLS client, bounded by the dial timeout.
+func tlsHandshake(cfg Config, conn net.Conn, tc *tls.Config) (net.Conn, error) {
+	c := tls.Client(conn, tc)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.dialTimeout())
+	defer cancel()
+	if err := c.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package discover
+
+import (
+	"fmt"
+	"slices"
+)
+
+// --------- TOUR PLANNING ---------
+
+// DistanceMatrix holds the straight-line distance between every pair of
+// planets in Names: Dist[i][j] is from Names[i] to Names[j].
+type DistanceMatrix struct {
+	Names []string
+	Dist  [][]float64
+}
+
+// PlanetDistanceMatrix returns the distance matrix over the named planets, or
+// over every planet (sorted by name) if none are given.
+func (d *Discover) PlanetDistanceMatrix(names ...string) (DistanceMatrix, error) {
+	var planets []PlanetRecord
+	if len(names) == 0 {
+		planets = d.PlanetList()
+	}
+	for _, name := range names {
+		p, ok := d.LookupPlanet(name)
+		if !ok {
+			return DistanceMatrix{}, fmt.Errorf("planet %s not found", name)
+		}
+		planets = append(planets, p)
+	}
+	m := DistanceMatrix{Names: make([]string, len(planets)), Dist: make([][]float64, len(planets))}
+	for i, p := range planets {
+		m.Names[i] = p.Name
+		m.Dist[i] = make([]float64, len(planets))
+		for j := range planets {
+			m.Dist[i][j] = distance3(p.Coordinates, planets[j].Coordinates)
+		}
+	}
+	return m, nil
+}
+
+// Tour returns an open visiting order over every planet in the matrix,
+// starting at start: a nearest-neighbour tour improved with 2-opt until no
+// swap shortens it. Add the leg back to start for a round trip.
+func (m DistanceMatrix) Tour(start string) (Route, error) {
+	s := slices.Index(m.Names, start)
+	if s < 0 {
+		return Route{}, fmt.Errorf("planet %s not found", start)
+	}
+	n := len(m.Names)
+	order := []int{s}
+	used := make([]bool, n)
+	used[s] = true
+	for len(order) < n {
+		last, next := order[len(order)-1], -1
+		for j := 0; j < n; j++ {
+			if !used[j] && (next < 0 || m.Dist[last][j] < m.Dist[last][next]) {
+				next = j
+			}
+		}
+		used[next] = true
+		order = append(order, next)
+	}
+
+	// 2-opt: reverse order[i..j] when that shortens the path. order[0] stays put.
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				a, b, c := order[i-1], order[i], order[j]
+				delta := m.Dist[a][c] - m.Dist[a][b]
+				if j+1 < n {
+					e := order[j+1]
+					delta += m.Dist[b][e] - m.Dist[c][e]
+				}
+				if delta < -1e-9 {
+					slices.Reverse(order[i : j+1])
+					improved = true
+				}
+			}
+		}
+	}
+
+	r := Route{Planets: make([]string, n)}
+	for k, i := range order {
+		r.Planets[k] = m.Names[i]
+		if k > 0 {
+			r.Distance += m.Dist[order[k-1]][i]
+		}
+	}
+	return r, nil
+}
+
+// PlanTour plans a survey tour from start over the named planets (every
+// planet if none are given); start is added if missing.
+func (d *Discover) PlanTour(start string, names ...string) (Route, error) {
+	if len(names) > 0 && !slices.Contains(names, start) {
+		names = append([]string{start}, names...)
+	}
+	m, err := d.PlanetDistanceMatrix(names...)
+	if err != nil {
+		return Route{}, err
+	}
+	return m.Tour(start)
+}
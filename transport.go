@@ -0,0 +1,179 @@
+package discover
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// msgTransport moves whole protocol messages to and from a pod. The scan and
+// command logic only talks to this interface, so TCP framing and WebSocket
+// frames share everything above the wire.
+type msgTransport interface {
+	sendMsg(msg string) error
+	readMsg() (string, error)
+	setTimeout(d time.Duration)
+	Close() error
+}
+
+// dialTransport opens the transport matching the target. Hosts written as
+// ws:// or wss:// URLs use WebSocket, unix:// URLs a Unix domain socket with
+// the usual framing, and everything else is TCP (over TLS if Config.TLS is set).
+func dialTransport(cfg Config, pod PodRef) (msgTransport, error) {
+	if isWebSocketURL(pod.Host) {
+		return dialWebSocket(cfg, pod.Host)
+	}
+	network, addr := "tcp", pod.String()
+	if isUnixURL(pod.Host) {
+		network, addr = "unix", unixSocketPath(pod.Host)
+	}
+	conn, err := dialConn(cfg, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TLS != nil && network == "tcp" {
+		if conn, err = tlsHandshake(cfg, conn, cfg.tlsConfigFor(pod, pod.Host)); err != nil {
+			return nil, err
+		}
+	}
+	return newPodConn(conn, cfg), nil
+}
+
+// Dialer opens network connections. *net.Dialer and the dialers from
+// golang.org/x/net/proxy (SOCKS5 etc.) satisfy it, as does anything wrapping
+// an SSH client's Dial method. Dialers that also implement
+// DialContext(ctx, network, addr) get the dial timeout through the context.
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+type contextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// dialConn dials through Config.Dialer when set, bounded by the dial timeout,
+// and applies any bandwidth limits.
+func dialConn(cfg Config, network, addr string) (net.Conn, error) {
+	conn, err := dialRaw(cfg, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return throttle(cfg, conn), nil
+}
+
+func dialRaw(cfg Config, network, addr string) (net.Conn, error) {
+	timeout := cfg.dialTimeout()
+	if cfg.Dialer == nil {
+		return net.DialTimeout(network, addr, timeout)
+	}
+	if cd, ok := cfg.Dialer.(contextDialer); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return cd.DialContext(ctx, network, addr)
+	}
+	return cfg.Dialer.Dial(network, addr)
+}
+
+// --- Framing ---
+
+// Framing selects how messages are delimited on the wire.
+type Framing int
+
+const (
+	// FramingDelimiter terminates every message with Config.Delimiter (default).
+	FramingDelimiter Framing = iota
+	// FramingLengthPrefix prefixes every message with its length as a 4-byte big-endian uint32.
+	FramingLengthPrefix
+)
+
+// maxFrameSize caps length-prefixed frames so a corrupt header can't trigger a huge allocation.
+const maxFrameSize = 64 << 20
+
+var errFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// podConn is the TCP transport. It wraps a connection with a persistent buffered reader so bytes read
+// past the end of one message are kept for the next.
+type podConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	framing Framing
+	delim   string
+	timeout time.Duration // read timeout
+	wtime   time.Duration // write timeout; 0 = none
+}
+
+func newPodConn(conn net.Conn, cfg Config) *podConn {
+	return &podConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		framing: cfg.Framing,
+		delim:   cfg.Delimiter,
+		timeout: cfg.readTimeout(),
+		wtime:   cfg.writeTimeout(),
+	}
+}
+
+// foreverTimeout stands in for "no read deadline" on connections that sit
+// idle between messages: subscriptions and the multiplexed reader.
+const foreverTimeout = 100 * 365 * 24 * time.Hour
+
+// setTimeout changes the read timeout.
+func (c *podConn) setTimeout(d time.Duration) { c.timeout = d }
+
+func setWriteDeadline(conn net.Conn, d time.Duration) {
+	if d > 0 {
+		conn.SetWriteDeadline(time.Now().Add(d))
+	}
+}
+
+// hasBanner reports whether the peer sends data before we say anything.
+// Pods wait for the auth message, so a banner means another service.
+func (c *podConn) hasBanner(window time.Duration) bool {
+	c.conn.SetReadDeadline(time.Now().Add(window))
+	defer c.conn.SetReadDeadline(time.Time{})
+	_, err := c.reader.Peek(1)
+	return err == nil
+}
+
+func (c *podConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *podConn) sendMsg(msg string) error {
+	defer perfTrack("sendMsg")()
+	setWriteDeadline(c.conn, c.wtime)
+	if c.framing == FramingLengthPrefix {
+		frame := make([]byte, 4+len(msg))
+		binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+		copy(frame[4:], msg)
+		_, err := c.conn.Write(frame)
+		return err
+	}
+	return sendMsg(c.conn, msg, c.delim)
+}
+
+func (c *podConn) readMsg() (string, error) {
+	defer perfTrack("readMsg")()
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	if c.framing == FramingLengthPrefix {
+		var header [4]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return "", err
+		}
+		n := binary.BigEndian.Uint32(header[:])
+		if n > maxFrameSize {
+			return "", errFrameTooLarge
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return "", err
+		}
+		// Length-prefixed payloads are returned exactly; they may be binary.
+		return string(payload), nil
+	}
+	return readDelimited(c.reader, c.delim)
+}
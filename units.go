@@ -0,0 +1,309 @@
+package discover
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --------- UNITS ---------
+
+// UnitTemplate describes the units SpawnBatch creates. IDs come from
+// GenerateUnitID(Role, Domain, Gen, Version) plus a sequence number.
+type UnitTemplate struct {
+	Role, Domain string
+	Gen, Version int
+	Rotation     []float64 // Euler degrees for every unit; nil stands each one upright on the sphere (SurfaceMatrix)
+}
+
+// SpawnResult is the outcome for one unit of a SpawnBatch.
+type SpawnResult struct {
+	ID       string
+	Pod      PodRef
+	Position []float64
+	Rotation []float64
+	Error    string // spawn error, empty on success
+}
+
+func (r SpawnResult) String() string {
+	if r.Error != "" {
+		return fmt.Sprintf("%s on %s: %s", r.ID, r.Pod, r.Error)
+	}
+	return fmt.Sprintf("%s on %s at %v", r.ID, r.Pod, r.Position)
+}
+
+// SpawnBatch spawns n units around planetName: positions from
+// GenerateSpawnPositions, IDs from GenerateUnitID numbered from 1 (skipping
+// IDs in Cubes, the unit registry or reserved by another operation), each
+// created on the planet's pod as in SpawnCubeOn.
+// One unit failing doesn't stop the rest; see each SpawnResult.
+func (d *Discover) SpawnBatch(planetName string, n int, radius float64, template UnitTemplate) ([]SpawnResult, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative unit count %d", n)
+	}
+	planet, ok := d.LookupPlanet(planetName)
+	if !ok {
+		return nil, fmt.Errorf("planet %s not found", planetName)
+	}
+	positions, err := d.GenerateSpawnPositions(planetName, n, radius)
+	if err != nil {
+		return nil, err
+	}
+	base := GenerateUnitID(template.Role, template.Domain, template.Gen, template.Version)
+	seq := 0
+	// nextID reserves the ID it returns until the spawn is done, so
+	// concurrent batches can't pick it too.
+	nextID := func() (string, func()) {
+		for {
+			seq++
+			id := fmt.Sprintf("%s-%d", base, seq)
+			if _, taken := d.LookupCube(id); taken {
+				continue
+			}
+			if _, known := d.units.Get(id); known {
+				continue
+			}
+			if release, err := d.units.reserve(id, "spawning"); err == nil {
+				return id, release
+			}
+		}
+	}
+
+	results := make([]SpawnResult, len(positions))
+	for i, pos := range positions {
+		rot := template.Rotation
+		if rot == nil {
+			e := SurfaceMatrix(planet.Coordinates[:], pos, nil).Euler()
+			rot = []float64{noNegZero(e.Pitch), noNegZero(e.Yaw), noNegZero(e.Roll)}
+		}
+		id, release := nextID()
+		r := SpawnResult{ID: id, Pod: planet.PodRef, Position: pos, Rotation: rot}
+		if err := d.spawnOn(planet.PodRef, planetName, r.ID, pos, rot); err != nil {
+			r.Error = err.Error()
+		}
+		release()
+		results[i] = r
+	}
+	return results, nil
+}
+
+// noNegZero turns -0 into 0, so rotations print and encode as 0.
+func noNegZero(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	return v
+}
+
+// --- registry ---
+
+// UnitState is where a unit is in its lifecycle.
+type UnitState string
+
+const (
+	UnitSpawned   UnitState = "spawned"
+	UnitMoved     UnitState = "moved"
+	UnitDespawned UnitState = "despawned"
+)
+
+// Unit is the registry's record of one spawned cube.
+type Unit struct {
+	ID            string    `json:"id"`
+	Pod           PodRef    `json:"pod"`
+	Planet        string    `json:"planet,omitempty"`
+	SpawnPosition Vec3      `json:"spawn_position"`
+	Position      Vec3      `json:"position"` // last position set through Discover
+	State         UnitState `json:"state"`
+	SpawnedAt     time.Time `json:"spawned_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// UnitRegistry remembers every cube spawned through SpawnCubeOn or SpawnBatch:
+// its pod, planet and spawn position, and whether it has since been moved
+// (MoveCube) or despawned (DespawnEverywhere). Despawned units are kept. The
+// registry is saved in snapshots and is safe for concurrent use.
+type UnitRegistry struct {
+	mu    sync.Mutex
+	units map[string]Unit
+	busy  map[string]string // ID -> operation holding it, see reserve
+}
+
+// Units returns d's unit registry.
+func (d *Discover) Units() *UnitRegistry { return &d.units }
+
+// Get returns the unit with the given ID.
+func (r *UnitRegistry) Get(id string) (Unit, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.units[id]
+	return u, ok
+}
+
+// List returns every unit, sorted by ID.
+func (r *UnitRegistry) List() []Unit {
+	return r.filter(func(Unit) bool { return true })
+}
+
+// Active returns the units not despawned, sorted by ID.
+func (r *UnitRegistry) Active() []Unit {
+	return r.filter(func(u Unit) bool { return u.State != UnitDespawned })
+}
+
+// OnPod returns the active units on pod, sorted by ID.
+func (r *UnitRegistry) OnPod(pod PodRef) []Unit {
+	return r.filter(func(u Unit) bool { return u.State != UnitDespawned && u.Pod == pod })
+}
+
+// Register adds or replaces a unit, for units created outside Discover.
+// UpdatedAt is set to now.
+func (r *UnitRegistry) Register(u Unit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u.UpdatedAt = time.Now().UTC()
+	if u.SpawnedAt.IsZero() {
+		u.SpawnedAt = u.UpdatedAt
+	}
+	r.putLocked(u)
+}
+
+// Forget drops a unit from the registry.
+func (r *UnitRegistry) Forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.units, id)
+}
+
+func (r *UnitRegistry) filter(keep func(Unit) bool) []Unit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []Unit{}
+	for _, u := range r.units {
+		if keep(u) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (r *UnitRegistry) putLocked(u Unit) {
+	if r.units == nil {
+		r.units = make(map[string]Unit)
+	}
+	r.units[u.ID] = u
+}
+
+// spawned records a new unit.
+func (r *UnitRegistry) spawned(id string, pod PodRef, planet string, pos []float64) {
+	now := time.Now().UTC()
+	p := Vec3Of(pos)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.putLocked(Unit{ID: id, Pod: pod, Planet: planet, SpawnPosition: p, Position: p, State: UnitSpawned, SpawnedAt: now, UpdatedAt: now})
+}
+
+// moved updates a known unit after a move; unknown IDs are ignored.
+func (r *UnitRegistry) moved(id string, pos []float64) {
+	r.update(id, func(u *Unit) { u.Position, u.State = Vec3Of(pos), UnitMoved })
+}
+
+// despawned marks a known unit as removed from pod.
+func (r *UnitRegistry) despawned(id string, pod PodRef) {
+	r.update(id, func(u *Unit) {
+		if u.Pod == pod {
+			u.State = UnitDespawned
+		}
+	})
+}
+
+// migrated records a unit's new pod after MigrateUnit.
+func (r *UnitRegistry) migrated(id string, pod PodRef, planet string, pos Vec3) {
+	r.update(id, func(u *Unit) { u.Pod, u.Planet, u.Position, u.State = pod, planet, pos, UnitMoved })
+}
+
+// reserve marks id as held by op until release is called, so MoveCube,
+// DespawnEverywhere and MigrateUnit don't act on the same cube at once. It
+// fails if another operation holds id. Cubes not in the registry can be
+// reserved too.
+func (r *UnitRegistry) reserve(id, op string) (release func(), err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if held, ok := r.busy[id]; ok {
+		return nil, fmt.Errorf("unit %s is busy %s", id, held)
+	}
+	if r.busy == nil {
+		r.busy = make(map[string]string)
+	}
+	r.busy[id] = op
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.busy, id)
+	}, nil
+}
+
+// claim reserves an active registered unit for migration.
+func (r *UnitRegistry) claim(id string) (Unit, func(), error) {
+	u, ok := r.Get(id)
+	switch {
+	case !ok:
+		return Unit{}, nil, fmt.Errorf("unit %s not found", id)
+	case u.State == UnitDespawned:
+		return Unit{}, nil, fmt.Errorf("unit %s is despawned", id)
+	}
+	release, err := r.reserve(id, "migrating")
+	if err != nil {
+		return Unit{}, nil, err
+	}
+	// Re-read under the reservation: the unit may have changed in between.
+	if u, ok = r.Get(id); !ok || u.State == UnitDespawned {
+		release()
+		return Unit{}, nil, fmt.Errorf("unit %s is despawned", id)
+	}
+	return u, release, nil
+}
+
+func (r *UnitRegistry) update(id string, f func(*Unit)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.units[id]
+	if !ok {
+		return
+	}
+	f(&u)
+	u.UpdatedAt = time.Now().UTC()
+	r.units[id] = u
+}
+
+// snapshot copies the registry, or returns nil when it is empty.
+func (r *UnitRegistry) snapshot() map[string]Unit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.units) == 0 {
+		return nil
+	}
+	out := make(map[string]Unit, len(r.units))
+	for id, u := range r.units {
+		out[id] = u
+	}
+	return out
+}
+
+// restore replaces the registry with a snapshot's.
+func (r *UnitRegistry) restore(units map[string]Unit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.units = units
+}
+
+// merge takes each unit from units unless the registry's copy is newer.
+func (r *UnitRegistry) merge(units map[string]Unit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, u := range units {
+		if mine, ok := r.units[id]; !ok || u.UpdatedAt.After(mine.UpdatedAt) {
+			r.putLocked(u)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package discover
+
+import "math"
+
+// --------- VECTORS ---------
+
+// Vec3 is a 3D point or direction. It converts freely to and from [3]float64;
+// the []float64 APIs remain as shims over it. Use Vec3Of to convert slices
+// without risking an index panic.
+type Vec3 [3]float64
+
+// V3 returns the vector (x, y, z).
+func V3(x, y, z float64) Vec3 { return Vec3{x, y, z} }
+
+// Vec3Of converts a slice, treating missing axes as 0 and ignoring extras.
+func Vec3Of(s []float64) Vec3 {
+	var v Vec3
+	copy(v[:], s)
+	return v
+}
+
+// Slice returns v as a new []float64 for the slice-based APIs.
+func (v Vec3) Slice() []float64 { return []float64{v[0], v[1], v[2]} }
+
+func (v Vec3) Add(w Vec3) Vec3         { return Vec3{v[0] + w[0], v[1] + w[1], v[2] + w[2]} }
+func (v Vec3) Sub(w Vec3) Vec3         { return Vec3{v[0] - w[0], v[1] - w[1], v[2] - w[2]} }
+func (v Vec3) Scale(s float64) Vec3    { return Vec3{v[0] * s, v[1] * s, v[2] * s} }
+func (v Vec3) Dot(w Vec3) float64      { return v[0]*w[0] + v[1]*w[1] + v[2]*w[2] }
+func (v Vec3) Cross(w Vec3) Vec3       { return cross3(v, w) }
+func (v Vec3) Len() float64            { return math.Sqrt(v.Dot(v)) }
+func (v Vec3) Distance(w Vec3) float64 { return v.Sub(w).Len() }
+
+// Normalize returns v scaled to unit length; the zero vector stays zero.
+func (v Vec3) Normalize() Vec3 { return normalize3(v) }
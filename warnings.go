@@ -0,0 +1,118 @@
+package discover
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --------- SCAN WARNINGS ---------
+
+// Warning kinds.
+const (
+	WarnTruncated      = "truncated"       // a reply was cut off; the scan redialed and resumed
+	WarnUnknownField   = "unknown_field"   // a planet carried fields this package doesn't know
+	WarnBadCoordinates = "bad_coordinates" // a planet's position is missing, incomplete or not finite (see CoordinatePolicy)
+	WarnStorage        = "storage"         // saving the scan to Config.History or the history store failed (Pod is empty)
+)
+
+// Warning is a non-fatal problem noticed while scanning a pod.
+type Warning struct {
+	Pod     PodRef
+	Kind    string
+	Command string // request the problem was seen in
+	Planet  string // planet name, if the warning is about one
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Planet != "" {
+		return fmt.Sprintf("%s %s: %s planet %s: %s", w.Pod, w.Kind, w.Command, w.Planet, w.Message)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", w.Pod, w.Kind, w.Command, w.Message)
+}
+
+// warningBuffer is the capacity of the Warnings channel; warnings beyond it
+// are dropped; PodResult.Warnings still has them all.
+const warningBuffer = 256
+
+// Warnings returns a channel receiving every warning from subsequent scans.
+// Sends never block. Warnings are also kept in PodResult.Warnings.
+func (d *Discover) Warnings() <-chan Warning {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.warnings == nil {
+		d.warnings = make(chan Warning, warningBuffer)
+	}
+	return d.warnings
+}
+
+// emitWarningsLocked forwards a result's warnings. d.mu must be held.
+func (d *Discover) emitWarningsLocked(res PodResult) {
+	if d.warnings == nil {
+		return
+	}
+	for _, w := range res.Warnings {
+		select {
+		case d.warnings <- w:
+		default:
+		}
+	}
+}
+
+// --- planet checks ---
+
+var knownPlanetFields = map[string]bool{
+	"position": true, "seed": true, "name": true,
+	"resourcelocations": true, "treelocations": true, "biometype": true,
+}
+
+// UnmarshalJSON decodes a planet and remembers any fields it doesn't know.
+// Known-only objects decode once; unknown fields cost a second pass.
+func (p *Planet) UnmarshalJSON(b []byte) error {
+	type plain Planet
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode((*plain)(p)); err == nil {
+		return nil
+	}
+	*p = Planet{}
+	if err := json.Unmarshal(b, (*plain)(p)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for k := range fields {
+		if !knownPlanetFields[strings.ToLower(k)] {
+			p.unknown = append(p.unknown, k)
+		}
+	}
+	sort.Strings(p.unknown)
+	return nil
+}
+
+// planetWarnings checks a decoded planet for problems that would otherwise
+// be silently flattened into PlanetRecord.
+func planetWarnings(p Planet, pod PodRef) []Warning {
+	var out []Warning
+	warn := func(kind, msg string) {
+		out = append(out, Warning{Pod: pod, Kind: kind, Command: StepPlanets, Planet: p.Name, Message: msg})
+	}
+	if len(p.unknown) > 0 {
+		warn(WarnUnknownField, "unknown fields "+strings.Join(p.unknown, ", "))
+	}
+	if p.Position == nil {
+		warn(WarnBadCoordinates, "no Position; using 0,0,0")
+		return out
+	}
+	for _, axis := range []string{"x", "y", "z"} {
+		if _, ok := p.Position[axis]; !ok {
+			warn(WarnBadCoordinates, "Position has no "+axis+"; using 0")
+		}
+	}
+	return out // non-finite values are handled by sanitizePlanet
+}
@@ -0,0 +1,150 @@
+package discover
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --------- CUBE WATCHER ---------
+
+// WatchCubes is the fallback for pods without Subscribe: it polls
+// get_cube_state and reports only real moves. Each cube's first good poll is
+// its baseline; later polls are compared with the last position reported, so
+// jitter below Threshold never adds up to an event but slow drift does.
+
+// watchWorkers bounds the get_cube_state calls in flight per poll.
+const watchWorkers = 16
+
+// WatchOptions tunes WatchCubes.
+type WatchOptions struct {
+	Interval  time.Duration // time between polls; 0 = 1s
+	Threshold float64       // minimum distance moved to report; 0 reports any change
+	Buffer    int           // channel capacity; 0 = 64
+}
+
+// CubeMove is a cube seen more than Threshold from where it was last reported,
+// or (Err set) a cube whose state could not be read.
+type CubeMove struct {
+	Cube     string
+	From, To Vec3
+	Distance float64
+	State    CubeState // the poll that triggered the event
+	Err      error
+}
+
+// WatchCubes polls the named cubes (every cube in Cubes when none are named,
+// re-read each poll) and sends a CubeMove when one has moved beyond
+// opts.Threshold. A failing cube is reported once, not on every poll, until it
+// reads again. The channel is closed when ctx is cancelled.
+func (d *Discover) WatchCubes(ctx context.Context, opts WatchOptions, names ...string) <-chan CubeMove {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	ch := make(chan CubeMove, opts.Buffer)
+	go func() {
+		defer close(ch)
+		last := make(map[string]Vec3) // position last reported (or baseline)
+		failing := make(map[string]bool)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			for _, ev := range d.pollCubes(ctx, d.watched(names), last, failing, opts.Threshold) {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
+
+// watched returns names, or every known cube sorted when names is empty.
+func (d *Discover) watched(names []string) []string {
+	if len(names) > 0 {
+		return names
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	all := make([]string, 0, len(d.Cubes))
+	for name := range d.Cubes {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	return all
+}
+
+// pollCubes reads every cube once and returns the events due, in names order.
+// Cubes no longer in names are dropped from last and failing.
+func (d *Discover) pollCubes(ctx context.Context, names []string, last map[string]Vec3, failing map[string]bool, threshold float64) []CubeMove {
+	states := make([]CubeState, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, watchWorkers)
+	var wg sync.WaitGroup
+spawn:
+	for i, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break spawn
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			states[i], errs[i] = d.GetCubeStateContext(ctx, name)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	current := make(map[string]bool, len(names))
+	for _, name := range names {
+		current[name] = true
+	}
+	for name := range last {
+		if !current[name] {
+			delete(last, name)
+		}
+	}
+	for name := range failing {
+		if !current[name] {
+			delete(failing, name)
+		}
+	}
+
+	var events []CubeMove
+	for i, name := range names {
+		if errs[i] != nil {
+			if !failing[name] {
+				failing[name] = true
+				events = append(events, CubeMove{Cube: name, Err: errs[i]})
+			}
+			continue
+		}
+		delete(failing, name)
+		s := states[i]
+		from, seen := last[name]
+		if !seen {
+			last[name] = s.Position
+			continue
+		}
+		if dist := from.Distance(s.Position); dist > threshold {
+			last[name] = s.Position
+			events = append(events, CubeMove{Cube: name, From: from, To: s.Position, Distance: dist, State: s})
+		}
+	}
+	return events
+}
@@ -0,0 +1,246 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- WebSocket transport ---
+//
+// A minimal RFC 6455 client: enough to exchange text messages with a pod that
+// sits behind an HTTP ingress. Each WebSocket message carries one protocol
+// message, so no delimiter or length prefix is added on send; a trailing
+// Config.Delimiter in a reply is stripped for pods that still emit it.
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+func isWebSocketURL(host string) bool {
+	return strings.HasPrefix(host, "ws://") || strings.HasPrefix(host, "wss://")
+}
+
+// webSocketPort returns the port a ws:// or wss:// URL connects to.
+func webSocketPort(rawURL string) int {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	if p := u.Port(); p != "" {
+		n, _ := strconv.Atoi(p)
+		return n
+	}
+	if u.Scheme == "wss" {
+		return 443
+	}
+	return 80
+}
+
+type wsConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	delim   string
+	timeout time.Duration // read timeout
+	wtime   time.Duration // write timeout; 0 = none
+}
+
+func dialWebSocket(cfg Config, rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(u.Hostname(), strconv.Itoa(webSocketPort(rawURL)))
+	conn, err := dialConn(cfg, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		conn, err = tlsHandshake(cfg, conn, cfg.tlsConfigFor(PodRef{Host: rawURL, Port: webSocketPort(rawURL)}, u.Hostname()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := &wsConn{conn: conn, reader: bufio.NewReader(conn), delim: cfg.Delimiter, timeout: cfg.readTimeout(), wtime: cfg.writeTimeout()}
+	if err := c.handshake(u); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *wsConn) handshake(u *url.URL) error {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	path := u.RequestURI()
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := io.WriteString(c.conn, req); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(c.reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("websocket handshake: unexpected status %s", resp.Status)
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return errors.New("websocket handshake: bad Sec-WebSocket-Accept")
+	}
+	return nil
+}
+
+func (c *wsConn) setTimeout(d time.Duration) { c.timeout = d }
+
+func (c *wsConn) Close() error {
+	c.writeFrame(wsOpClose, nil)
+	return c.conn.Close()
+}
+
+func (c *wsConn) sendMsg(msg string) error {
+	return c.writeFrame(wsOpText, []byte(msg))
+}
+
+func (c *wsConn) readMsg() (string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	var msg bytes.Buffer
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return "", err
+		}
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return "", err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return "", io.EOF
+		}
+		msg.Write(payload)
+		if msg.Len() > maxFrameSize {
+			return "", errFrameTooLarge
+		}
+		if fin {
+			break
+		}
+	}
+	out := msg.String()
+	if c.delim != "" {
+		out = strings.TrimSuffix(out, c.delim)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// writeFrame sends a single, final, masked frame as clients must.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	setWriteDeadline(c.conn, c.wtime)
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	header = append(header, mask[:]...)
+	frame := make([]byte, len(header)+len(payload))
+	copy(frame, header)
+	for i, b := range payload {
+		frame[len(header)+i] = b ^ mask[i%4]
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.reader, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	switch op {
+	case wsOpContinuation, wsOpText, wsOpBinary, wsOpClose, wsOpPing, wsOpPong:
+	default: // 0x3-0x7 and 0xB-0xF are reserved
+		err = fmt.Errorf("websocket: unknown opcode %d", op)
+		return
+	}
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxFrameSize {
+		err = errFrameTooLarge
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}